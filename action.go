@@ -8,9 +8,9 @@ import (
 	"strings"
 
 	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"gopkg.in/yaml.v3"
 )
 
 type (
@@ -54,6 +54,9 @@ func ActionMacro(s string, a ...any) carapace.Action {
 
 		switch {
 		case !strings.HasPrefix(matches[1], "_.") && strings.Contains(matches[1], ".") && !strings.HasPrefix(s, prefix):
+			if err := checkTrust(c.Getenv(untrustedEnv)); err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
 			splitted := strings.SplitN(strings.TrimPrefix(s, "$"), ".", 2)
 			args := []string{"_carapace", "macro"}
 			args = append(args, splitted[1])
@@ -85,20 +88,19 @@ func ActionSpec(path string) carapace.Action {
 			return carapace.ActionMessage(err.Error())
 		}
 
-		content, err := os.ReadFile(abs)
+		cmd, _, err := command.LoadWithOverlays(abs) // skipped overlays are reported by `carapace-spec merge`
 		if err != nil {
 			return carapace.ActionMessage(err.Error())
 		}
-
-		var cmd Command
-		if err := yaml.Unmarshal(content, &cmd); err != nil {
-			return carapace.ActionMessage(err.Error())
-		}
-
-		cmdCobra, err := cmd.ToCobraE()
+		cmdCobra, err := Command(*cmd).ToCobraE()
 		if err != nil {
 			return carapace.ActionMessage(err.Error())
 		}
+		if _, ok := c.LookupEnv(untrustedEnv); ok { // verify specs loaded by a verified one
+			if err := VerifyTrust(cmd, cmdCobra); err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+		}
 		return carapace.ActionExecute(cmdCobra)
 	})
 }
@@ -111,59 +113,54 @@ func (a action) Parse(cmd *cobra.Command) carapace.Action {
 			c.Setenv(fmt.Sprintf("C_ARG%v", index), arg)
 		}
 		c.Setenv("C_VALUE", c.Value)
+		setTrustEnv(cmd, &c)
 
 		cmd.Flags().VisitAll(func(f *pflag.Flag) { // VisitAll as Visit() skips changed persistent flags of parent commands
 			if f.Changed {
 				c.Setenv(fmt.Sprintf("C_FLAG_%v", strings.ToUpper(f.Name)), f.Value.String())
+			} else if value, ok := flagEnv(f, c.LookupEnv); ok {
+				c.Setenv(fmt.Sprintf("C_FLAG_%v", strings.ToUpper(f.Name)), value)
 			}
 		})
 
 		batch := carapace.Batch()
+		batchID := cmd.CommandPath() // identifies the batch by its substituted values
+		for _, elem := range a {
+			if elemSubst, err := c.Envsubst(string(elem)); err == nil {
+				batchID += fmt.Sprintf(" %q", elemSubst)
+			}
+		}
 		batchAction := carapace.ActionCallback(func(c carapace.Context) carapace.Action {
 			return batch.ToA()
 		})
 
 		for _, elem := range a {
-			elemSubst, err := c.Envsubst(string(elem))
-			if err != nil {
-				batch = append(batch, carapace.ActionMessage("%v: %#v", err.Error(), elem))
-				continue
+			elemSubst := string(elem)
+			if !strings.HasPrefix(elemSubst, "$exec(") { // `$exec` substitutes its arguments separately
+				var err error
+				if elemSubst, err = c.Envsubst(elemSubst); err != nil {
+					batch = append(batch, carapace.ActionMessage("%v: %#v", err.Error(), elem))
+					continue
+				}
 			}
 
 			splitted := strings.Split(elemSubst, " ||| ")
+			id := fmt.Sprintf("%v %q", cmd.CommandPath(), elemSubst)
 
 			if strings.HasPrefix(splitted[0], "$") { // macro
-				switch strings.SplitN(splitted[0], "(", 2)[0] {
-				case // generic modifier applied to batch
-					"$chdir",
-					"$filter",
-					"$filterargs",
-					"$list",
-					"$multiparts",
-					"$nospace",
-					"$noprefix",
-					"$prefix",
-					"$retain",
-					"$shift",
-					"$split",
-					"$splitp",
-					"$suffix",
-					"$suppress",
-					"$style",
-					"$tag",
-					"$uniquelist",
-					"$usage":
-					batchAction = modifier{batchAction}.Parse(splitted[0])
+				switch {
+				case isModifier(splitted[0]): // generic modifier applied to batch
+					batchAction = modifier{batchAction, batchID}.Parse(splitted[0])
 					if len(splitted) > 1 {
 						for _, m := range splitted[1:] {
-							batchAction = modifier{batchAction}.Parse(m)
+							batchAction = modifier{batchAction, batchID}.Parse(m)
 						}
 					}
 				default:
 					a := ActionMacro(splitted[0])
 					if len(splitted) > 1 {
 						for _, m := range splitted[1:] {
-							a = modifier{a}.Parse(m)
+							a = modifier{a, id}.Parse(m)
 						}
 					}
 					batch = append(batch, a)
@@ -172,7 +169,7 @@ func (a action) Parse(cmd *cobra.Command) carapace.Action {
 				a := parseValue(splitted[0])
 				if len(splitted) > 1 {
 					for _, m := range splitted[1:] {
-						a = modifier{a}.Parse(m)
+						a = modifier{a, id}.Parse(m)
 					}
 				}
 				batch = append(batch, a)
@@ -0,0 +1,112 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/spf13/cobra"
+)
+
+// addArgs validates the positional arguments in runnable mode.
+func (c Command) addArgs(cmd *cobra.Command) error {
+	if c.Args.IsZero() {
+		return nil
+	}
+
+	validators := make([]cobra.PositionalArgs, 0)
+	switch a := c.Args; {
+	case a.None:
+		validators = append(validators, cobra.NoArgs)
+	case a.Exact > 0:
+		validators = append(validators, cobra.ExactArgs(a.Exact))
+	case a.Min > 0 && a.Max > 0:
+		validators = append(validators, cobra.RangeArgs(a.Min, a.Max))
+	case a.Min > 0:
+		validators = append(validators, cobra.MinimumNArgs(a.Min))
+	case a.Max > 0:
+		validators = append(validators, cobra.MaximumNArgs(a.Max))
+	}
+	if c.Args.Valid {
+		validators = append(validators, ValidArgs(c.validArgs()))
+	}
+	cmd.Args = PositionalArgs(validators...)
+	return nil
+}
+
+// validArgs returns the static values of the positional completion (nil if any value is valid).
+func (c Command) validArgs() (positional [][]string, other []string) {
+	for _, a := range c.Completion.Positional {
+		values, _ := a.StaticValues()
+		positional = append(positional, values)
+	}
+	other, _ = command.Action{Values: c.Completion.PositionalAny}.StaticValues()
+	return
+}
+
+// formatArgs formats the validation of positional arguments for codegen.
+func (c Command) formatArgs() string {
+	validators := make([]string, 0)
+	switch a := c.Args; {
+	case a.None:
+		validators = append(validators, "cobra.NoArgs")
+	case a.Exact > 0:
+		validators = append(validators, fmt.Sprintf("cobra.ExactArgs(%v)", a.Exact))
+	case a.Min > 0 && a.Max > 0:
+		validators = append(validators, fmt.Sprintf("cobra.RangeArgs(%v, %v)", a.Min, a.Max))
+	case a.Min > 0:
+		validators = append(validators, fmt.Sprintf("cobra.MinimumNArgs(%v)", a.Min))
+	case a.Max > 0:
+		validators = append(validators, fmt.Sprintf("cobra.MaximumNArgs(%v)", a.Max))
+	}
+	if c.Args.Valid {
+		positional, other := c.validArgs()
+		formatted := make([]string, 0, len(positional))
+		for _, values := range positional {
+			formatted = append(formatted, strings.TrimPrefix(formatValues(values), "[]string"))
+		}
+		validators = append(validators, fmt.Sprintf("spec.ValidArgs([][]string{%v}, %v)", strings.Join(formatted, ", "), formatValues(other)))
+	}
+	return fmt.Sprintf("spec.PositionalArgs(%v)", strings.Join(validators, ", "))
+}
+
+func formatValues(values []string) string {
+	if values == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("[]string{%v}", quoteAll(values))
+}
+
+// PositionalArgs applies given validators to the positional arguments (those before `--`).
+func PositionalArgs(validators ...cobra.PositionalArgs) cobra.PositionalArgs {
+	validate := cobra.MatchAll(validators...)
+	return func(cmd *cobra.Command, args []string) error {
+		switch dash := cmd.ArgsLenAtDash(); {
+		case dash >= 0:
+			args = args[:dash]
+		case cmd.DisableFlagParsing:
+			if index := slices.Index(args, "--"); index >= 0 {
+				args = args[:index]
+			}
+		}
+		return validate(cmd, args)
+	}
+}
+
+// ValidArgs restricts the positional arguments to given values by position (nil accepts any value).
+// Values of `other` apply to any further position.
+func ValidArgs(positional [][]string, other []string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		for index, arg := range args {
+			values := other
+			if index < len(positional) {
+				values = positional[index]
+			}
+			if values != nil && !slices.Contains(values, arg) {
+				return fmt.Errorf("invalid argument %q for %q", arg, cmd.CommandPath())
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+package spec
+
+import (
+	"os"
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestArgs(t *testing.T) {
+	content, err := os.ReadFile("example/args.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c Command
+	if err := yaml.Unmarshal(content, &c); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := c.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validate := func(args ...string) string {
+		subcmd, _, err := cmd.Find(args[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := subcmd.ValidateArgs(args[1:]); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+
+	assert.Equal(t, "", validate("none"))
+	assert.Equal(t, `unknown command "x" for "args none"`, validate("none", "x"))
+	assert.Equal(t, "", validate("exact", "1", "2"))
+	assert.Equal(t, "", validate("exact", "1", "2", "--", "3"))
+	assert.Equal(t, "accepts 2 arg(s), received 1", validate("exact", "1"))
+	assert.Equal(t, "accepts between 1 and 2 arg(s), received 0", validate("range"))
+	assert.Equal(t, "accepts between 1 and 2 arg(s), received 3", validate("range", "1", "2", "3"))
+	assert.Equal(t, "", validate("valid", "one", "any", "three", "four"))
+	assert.Equal(t, `invalid argument "first value" for "args valid"`, validate("valid", "first value"))
+	assert.Equal(t, `invalid argument "five" for "args valid"`, validate("valid", "two", "any", "five"))
+}
@@ -1,7 +1,15 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
 	"github.com/carapace-sh/carapace"
+	spec "github.com/carapace-sh/carapace-spec"
 	"github.com/spf13/cobra"
 )
 
@@ -10,17 +18,59 @@ var codegenCmd = &cobra.Command{
 	Short: "generate code for spec file",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+		pkg, _ := cmd.Flags().GetString("package")
+		singleFile, _ := cmd.Flags().GetBool("single-file")
+		check, _ := cmd.Flags().GetBool("check")
+
+		if check && out == "" {
+			return errors.New("flag --check requires --out")
+		}
+
 		command, err := loadSpec(args[0])
 		if err != nil {
 			return err
 		}
-		return command.Codegen()
+
+		files, err := command.GenerateCode(spec.CodegenOptions{
+			Package:    pkg,
+			SingleFile: singleFile,
+		})
+		if err != nil {
+			return err
+		}
+
+		if check {
+			return spec.CheckCode(out, files)
+		}
+
+		if out == "" {
+			if out, err = os.MkdirTemp(os.TempDir(), "carapace-codegen-"); err != nil {
+				return err
+			}
+		}
+		if err := spec.WriteCode(out, files); err != nil {
+			return err
+		}
+		for _, name := range slices.Sorted(maps.Keys(files)) {
+			fmt.Fprintln(cmd.OutOrStdout(), filepath.Join(out, name))
+		}
+		return nil
 	},
 }
 
 func init() {
+	codegenCmd.Flags().Bool("check", false, "fail if the generated code in the output directory is stale")
+	codegenCmd.Flags().String("out", "", "output directory (default: temporary directory)")
+	codegenCmd.Flags().String("package", "cmd", "package name")
+	codegenCmd.Flags().Bool("single-file", false, "generate a single file")
+
 	rootCmd.AddCommand(codegenCmd)
 
+	carapace.Gen(codegenCmd).FlagCompletion(carapace.ActionMap{
+		"out": carapace.ActionDirectories(),
+	})
+
 	carapace.Gen(codegenCmd).PositionalCompletion(
 		carapace.ActionFiles(".yaml"),
 	)
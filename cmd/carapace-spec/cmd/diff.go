@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printDiff prints the line based difference in unified format.
+func printDiff(w io.Writer, from, to, a, b string) {
+	const context = 3
+
+	x := strings.SplitAfter(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.SplitAfter(strings.TrimSuffix(b, "\n"), "\n")
+
+	// longest common subsequence
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			default:
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+		i, j int // line numbers in a and b
+	}
+	lines := make([]line, 0)
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, line{' ', x[i], i, j})
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]): // removals first
+			lines = append(lines, line{'-', x[i], i, j})
+			i++
+		default:
+			lines = append(lines, line{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	fmt.Fprintf(w, "--- %v\n+++ %v\n", from, to)
+	for start := 0; start < len(lines); {
+		if lines[start].op == ' ' {
+			start++
+			continue
+		}
+
+		// extend hunk while changes are within context
+		from := max(0, start-context)
+		end := start
+		for k := start; k < len(lines) && k <= end+2*context; k++ {
+			if lines[k].op != ' ' {
+				end = k
+			}
+		}
+		to := min(len(lines), end+context+1)
+
+		countA, countB := 0, 0
+		for _, l := range lines[from:to] {
+			if l.op != '+' {
+				countA++
+			}
+			if l.op != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(w, "@@ -%v,%v +%v,%v @@\n", lines[from].i+1, countA, lines[from].j+1, countB)
+		for _, l := range lines[from:to] {
+			fmt.Fprintf(w, "%c%v", l.op, strings.TrimSuffix(l.text, "\n")+"\n")
+		}
+		start = to
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace-spec/pkg/doc"
+	"github.com/spf13/cobra"
+)
+
+var docCmd = &cobra.Command{
+	Use:   "doc spec",
+	Short: "generate reference documentation for spec file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		out, _ := cmd.Flags().GetString("out")
+
+		specCmd, warnings, err := command.LoadWithOverlays(args[0]) // no trust needed as nothing is executed
+		if err != nil {
+			return err
+		}
+		printWarnings(warnings)
+
+		pages, err := doc.Generate(*specCmd, format)
+		if err != nil {
+			return err
+		}
+
+		if out == "" {
+			for index, name := range doc.Filenames(pages) {
+				if index > 0 {
+					fmt.Fprintln(cmd.OutOrStdout())
+				}
+				fmt.Fprint(cmd.OutOrStdout(), string(pages[name]))
+			}
+			return nil
+		}
+
+		if err := os.MkdirAll(out, 0755); err != nil {
+			return err
+		}
+		for _, name := range doc.Filenames(pages) {
+			if err := os.WriteFile(filepath.Join(out, name), pages[name], 0644); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), filepath.Join(out, name))
+		}
+		return nil
+	},
+}
+
+func init() {
+	docCmd.Flags().String("format", "markdown", "output format [man|markdown|html]")
+	docCmd.Flags().String("out", "", "output directory with a file per command (default: print to stdout)")
+
+	rootCmd.AddCommand(docCmd)
+
+	carapace.Gen(docCmd).FlagCompletion(carapace.ActionMap{
+		"format": carapace.ActionValues("man", "markdown", "html"),
+		"out":    carapace.ActionDirectories(),
+	})
+
+	carapace.Gen(docCmd).PositionalCompletion(
+		carapace.ActionFiles(".yaml"),
+	)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/spf13/cobra"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt spec...",
+	Short: "format spec files",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		check, _ := cmd.Flags().GetBool("check")
+
+		unformatted := false
+		for _, path := range args {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			formatted, err := command.Format(content)
+			if err != nil {
+				return fmt.Errorf("%v: %w", path, err)
+			}
+
+			switch {
+			case bytes.Equal(content, formatted):
+				continue
+			case check:
+				unformatted = true
+				printDiff(cmd.OutOrStdout(), path, path+" (formatted)", string(content), string(formatted))
+			default:
+				if err := os.WriteFile(path, formatted, 0644); err != nil {
+					return err
+				}
+			}
+		}
+
+		if unformatted {
+			cmd.SilenceUsage = true
+			return errors.New("specs are not formatted")
+		}
+		return nil
+	},
+}
+
+func init() {
+	fmtCmd.Flags().Bool("check", false, "print a diff and exit non-zero instead of rewriting files")
+
+	rootCmd.AddCommand(fmtCmd)
+
+	carapace.Gen(fmtCmd).PositionalAnyCompletion(
+		carapace.ActionFiles(".yaml").FilterArgs(),
+	)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint spec",
+	Short: "lint spec file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		command, err := loadSpec(args[0])
+		if err != nil {
+			return err
+		}
+
+		diagnostics := command.Lint()
+		switch format, _ := cmd.Flags().GetString("format"); format {
+		case "json":
+			m, err := json.MarshalIndent(diagnostics, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(m))
+		case "text":
+			for _, diagnostic := range diagnostics {
+				fmt.Fprintln(cmd.OutOrStdout(), diagnostic)
+			}
+		default:
+			return fmt.Errorf("unknown format: %v", format)
+		}
+
+		if len(diagnostics) > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%v diagnostic(s)", len(diagnostics))
+		}
+		return nil
+	},
+}
+
+func init() {
+	lintCmd.Flags().String("format", "text", "output format")
+
+	rootCmd.AddCommand(lintCmd)
+
+	carapace.Gen(lintCmd).FlagCompletion(carapace.ActionMap{
+		"format": carapace.ActionValues("text", "json"),
+	})
+
+	carapace.Gen(lintCmd).PositionalCompletion(
+		carapace.ActionFiles(".yaml"),
+	)
+}
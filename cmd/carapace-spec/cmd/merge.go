@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge spec [overlay]...",
+	Short: "print spec with overlays applied",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noDiscover, _ := cmd.Flags().GetBool("no-discover")
+
+		specCmd, err := command.Load(args[0]) // no trust needed as nothing is executed
+		if err != nil {
+			return err
+		}
+
+		files := make([]string, 0)
+		if !noDiscover {
+			if files, err = specCmd.OverlayFiles(); err != nil {
+				return err
+			}
+		}
+		for _, path := range args[1:] {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(files, abs) {
+				files = append(files, abs)
+			}
+		}
+
+		overlays := make([]command.Overlay, 0)
+		for _, file := range files {
+			o, err := command.LoadOverlays(file)
+			if err != nil {
+				return err
+			}
+			overlays = append(overlays, o...)
+		}
+
+		merged, err := specCmd.Apply(overlays...)
+		if err != nil {
+			return err
+		}
+
+		m, err := yaml.Marshal(merged)
+		if err != nil {
+			return err
+		}
+		formatted, err := command.Format(m)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), string(formatted))
+		return nil
+	},
+}
+
+func init() {
+	mergeCmd.Flags().Bool("no-discover", false, "only apply given overlays (skip those alongside the spec and in the config directory)")
+
+	rootCmd.AddCommand(mergeCmd)
+
+	carapace.Gen(mergeCmd).PositionalCompletion(
+		carapace.ActionFiles(".yaml"),
+	)
+
+	carapace.Gen(mergeCmd).PositionalAnyCompletion(
+		carapace.ActionFiles(".yaml").FilterArgs(),
+	)
+}
@@ -10,8 +10,8 @@ import (
 
 	"github.com/carapace-sh/carapace"
 	spec "github.com/carapace-sh/carapace-spec"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 var rootCmd = &cobra.Command{
@@ -38,33 +38,64 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		specCmd, err := loadSpec(abs)
+		specCmd, err := loadSpecWithOverlays(abs)
 		if err != nil {
 			return err
 		}
-		bridgeCompletion(specCmd.ToCobra(), abs, args[1:]...)
+		cobraCmd, err := verifiedCobra(specCmd)
+		if err != nil {
+			return err
+		}
+		bridgeCompletion(cobraCmd, abs, args[1:]...)
 		return nil
 	},
 }
 
+// loadSpec loads the spec at given path without overlays
+// (so that codegen, lint and test don't depend on the user config directory).
 func loadSpec(path string) (*spec.Command, error) {
-	abs, err := filepath.Abs(path)
+	cmd, err := command.Load(path)
 	if err != nil {
 		return nil, err
 	}
 
-	content, err := os.ReadFile(abs)
+	specCmd := spec.Command(*cmd)
+	return &specCmd, nil
+}
+
+// loadSpecWithOverlays loads the spec at given path with its overlays (for completion and run).
+func loadSpecWithOverlays(path string) (*spec.Command, error) {
+	abs, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var specCmd spec.Command
-	if err := yaml.Unmarshal(content, &specCmd); err != nil {
+	cmd, warnings, err := command.LoadWithOverlays(abs)
+	if err != nil {
 		return nil, err
 	}
+	printWarnings(warnings)
+
+	specCmd := spec.Command(*cmd)
 	return &specCmd, nil
 }
 
+// verifiedCobra creates the command of given spec with its trust verified (only needed where the spec is executed).
+func verifiedCobra(specCmd *spec.Command) (*cobra.Command, error) {
+	cmd := specCmd.ToCobra()
+	if err := spec.VerifyTrust((*command.Command)(specCmd), cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// printWarnings prints given warnings (e.g. of skipped overlays) to stderr.
+func printWarnings(warnings []error) {
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", warning)
+	}
+}
+
 func Execute(version string) error {
 	rootCmd.Version = version
 	return rootCmd.Execute()
@@ -78,7 +109,7 @@ func init() {
 
 	carapace.Gen(rootCmd).PositionalAnyCompletion(
 		carapace.ActionCallback(func(c carapace.Context) carapace.Action {
-			return spec.ActionSpec(c.Args[0]).Shift(1)
+			return spec.ActionVerifiedSpec(c.Args[0]).Shift(1)
 		}),
 	)
 
@@ -11,11 +11,14 @@ var runCmd = &cobra.Command{
 	Short: "run spec",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		command, err := loadSpec(args[0])
+		command, err := loadSpecWithOverlays(args[0])
+		if err != nil {
+			return err
+		}
+		cobraCmd, err := verifiedCobra(command)
 		if err != nil {
 			return err
 		}
-		cobraCmd := command.ToCobra()
 		cobraCmd.SetArgs(args[1:])
 		return cobraCmd.Execute()
 	},
@@ -32,7 +35,7 @@ func init() {
 
 	carapace.Gen(runCmd).PositionalAnyCompletion(
 		carapace.ActionCallback(func(c carapace.Context) carapace.Action {
-			return spec.ActionSpec(c.Args[0]).Shift(1)
+			return spec.ActionVerifiedSpec(c.Args[0]).Shift(1)
 		}),
 	)
 }
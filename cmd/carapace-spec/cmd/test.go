@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/carapace-sh/carapace"
+	spec "github.com/carapace-sh/carapace-spec"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test spec...",
+	Short: "test completions of specs with their sidecar test file",
+	Long: `Test completions of specs with their sidecar test file (example.yaml -> example.test.yaml):
+
+  - name: flag completion
+    args: [--color]
+    current: ""
+    expect:
+      values:
+        - value: always
+        - value: never`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		update, _ := cmd.Flags().GetBool("update")
+
+		failed := 0
+		for _, path := range args {
+			specCmd, err := loadSpec(path)
+			if err != nil {
+				return err
+			}
+
+			testPath := spec.TestPath(path)
+			content, err := os.ReadFile(testPath)
+			if err != nil {
+				return err
+			}
+
+			var testCases []spec.CompletionTest
+			if err := yaml.Unmarshal(content, &testCases); err != nil {
+				return fmt.Errorf("%v: %w", testPath, err)
+			}
+
+			for index, testCase := range testCases {
+				name := fmt.Sprintf("%v[%v]", testPath, index)
+				if testCase.Name != "" {
+					name += " " + testCase.Name
+				}
+
+				completion, err := specCmd.Complete(testCase.Args, testCase.Current)
+				if err != nil {
+					return fmt.Errorf("%v: %w", name, err)
+				}
+
+				if update {
+					testCases[index].Expect = *completion
+					continue
+				}
+
+				expected, err := marshalTest(testCase.Expect)
+				if err != nil {
+					return err
+				}
+				actual, err := marshalTest(*completion)
+				if err != nil {
+					return err
+				}
+
+				if expected == actual {
+					fmt.Fprintf(cmd.OutOrStdout(), "PASS %v\n", name)
+					continue
+				}
+				failed++
+				fmt.Fprintf(cmd.OutOrStdout(), "FAIL %v\n", name)
+				printDiff(cmd.OutOrStdout(), "expected", "actual", expected, actual)
+			}
+
+			if update {
+				updated, err := marshalTest(testCases)
+				if err != nil {
+					return err
+				}
+				if err := os.WriteFile(testPath, []byte(updated), 0644); err != nil {
+					return err
+				}
+			}
+		}
+
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%v failed", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	testCmd.Flags().Bool("update", false, "update expectations with the actual completions")
+
+	rootCmd.AddCommand(testCmd)
+
+	carapace.Gen(testCmd).PositionalAnyCompletion(
+		carapace.ActionFiles(".yaml").FilterArgs(),
+	)
+}
+
+// marshalTest marshals given value with the indentation used by `fmt`.
+func marshalTest(v any) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
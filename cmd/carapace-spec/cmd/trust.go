@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/carapace-sh/carapace"
+	spec "github.com/carapace-sh/carapace-spec"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace-spec/pkg/trust"
+	"github.com/spf13/cobra"
+)
+
+var trustCmd = &cobra.Command{
+	Use:   "trust spec...",
+	Short: "allow specs to execute commands",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := trust.Load()
+		if err != nil {
+			return err
+		}
+
+		revoke, _ := cmd.Flags().GetBool("revoke")
+		for _, path := range args {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+
+			if revoke {
+				delete(store, abs)
+				continue
+			}
+
+			specCmd, err := command.Load(abs)
+			if err != nil {
+				return err
+			}
+			if store[abs], err = spec.TrustHash(specCmd); err != nil { // overlays are part of the trusted content
+				return err
+			}
+		}
+		return store.Save()
+	},
+}
+
+func init() {
+	trustCmd.Flags().Bool("revoke", false, "revoke trust")
+
+	rootCmd.AddCommand(trustCmd)
+
+	carapace.Gen(trustCmd).PositionalAnyCompletion(
+		carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+			if revoke, _ := trustCmd.Flags().GetBool("revoke"); revoke {
+				store, err := trust.Load()
+				if err != nil {
+					return carapace.ActionMessage(err.Error())
+				}
+				return carapace.ActionValues(slices.Collect(maps.Keys(store))...).FilterArgs()
+			}
+			return carapace.ActionFiles(".yaml").FilterArgs()
+		}),
+	)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/carapace-sh/carapace-spec/cmd/carapace-spec/cmd"
@@ -14,5 +15,7 @@ func main() {
 	if strings.Contains(version, "SNAPSHOT") {
 		version += fmt.Sprintf(" (%v) [%v]", date, commit)
 	}
-	cmd.Execute(version)
+	if err := cmd.Execute(version); err != nil {
+		os.Exit(1)
+	}
 }
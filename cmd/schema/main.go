@@ -36,6 +36,57 @@ func main() {
 		},
 	}
 
+	// patch for dual type in Action
+	values := &jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{Type: "string"}}
+	conditional := jsonschema.NewProperties()
+	conditional.Set("when", &jsonschema.Schema{
+		Type:        "array",
+		Items:       &jsonschema.Schema{Ref: "#/$defs/Condition"},
+		Description: "Conditional completions of which the first matching one applies",
+	})
+	conditional.Set("else", &jsonschema.Schema{
+		Type:        "array",
+		Items:       &jsonschema.Schema{Type: "string"},
+		Description: "Completion if no condition applies",
+	})
+	schema.Definitions["Action"] = &jsonschema.Schema{
+		OneOf: []*jsonschema.Schema{
+			values,
+			{
+				Type:                 "object",
+				Properties:           conditional,
+				Required:             []string{"when"},
+				AdditionalProperties: jsonschema.FalseSchema,
+			},
+		},
+	}
+
+	// patch for includes
+	ref := jsonschema.NewProperties()
+	ref.Set(command.RefKey, &jsonschema.Schema{
+		Type:        "string",
+		Description: "Path to the included file (relative to the including file)",
+	})
+	schema.Definitions["Ref"] = &jsonschema.Schema{
+		Type:                 "object",
+		Properties:           ref,
+		Required:             []string{command.RefKey},
+		AdditionalProperties: jsonschema.FalseSchema,
+	}
+	for _, name := range []string{"flags", "persistentflags", "completion"} {
+		s, _ = schema.Definitions["Command"].Properties.Get(name)
+		inner := *s
+		inner.Description = ""
+		*s = jsonschema.Schema{
+			OneOf:       []*jsonschema.Schema{{Ref: "#/$defs/Ref"}, &inner},
+			Description: s.Description,
+		}
+	}
+	s, _ = schema.Definitions["Command"].Properties.Get("commands")
+	s.Items = &jsonschema.Schema{
+		OneOf: []*jsonschema.Schema{{Ref: "#/$defs/Ref"}, s.Items},
+	}
+
 	m, err := schema.MarshalJSON()
 	if err != nil {
 		panic(err.Error())
@@ -0,0 +1,250 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/carapace-sh/carapace-spec/internal/pflagfork"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	completionAnnotation = "carapace-spec_completion" // command annotation containing the completion of a spec (as yaml)
+	runAnnotation        = "carapace-spec_run"        // command annotation containing the run of a spec (as yaml)
+	argsAnnotation       = "carapace-spec_args"       // command annotation containing the args of a spec (as yaml)
+)
+
+// annotateSpec records completion, run and args so that FromCobra can restore them
+// (cobra only keeps the functions created from the latter).
+func (c Command) annotateSpec(cmd *cobra.Command) error {
+	for annotation, v := range map[string]any{
+		completionAnnotation: c.Completion,
+		runAnnotation:        c.Run,
+		argsAnnotation:       c.Args,
+	} {
+		if reflect.ValueOf(v).IsZero() {
+			continue
+		}
+
+		m, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if cmd.Annotations == nil {
+			cmd.Annotations = make(map[string]string)
+		}
+		cmd.Annotations[annotation] = string(m)
+	}
+	return nil
+}
+
+// FromCobra creates a spec from given command (inverse of Command.ToCobraE).
+//
+// Completion, run and args of commands created by Command.ToCobraE are restored from their annotations.
+// Otherwise completions are derived from cobra annotations (filename extensions, subdirectories) and `ValidArgs`.
+// Actions registered with carapace directly can't be mapped as carapace doesn't expose them,
+// neither can cobra completion functions as they are dynamic. Both are omitted.
+//
+// Flag types, defaults, enum values and environment variables are derived from the flag values.
+// The documentation of flags and arguments is lossy as it is only part of the help output.
+func FromCobra(cmd *cobra.Command) command.Command {
+	c := command.Command{
+		Name:        cmd.Use,
+		Aliases:     cmd.Aliases,
+		Description: cmd.Short,
+		Group:       cmd.GroupID,
+		Hidden:      cmd.Hidden,
+		Deprecated:  cmd.Deprecated,
+	}
+	c.Documentation.Command = cmd.Long
+	c.Examples = parseExamples(cmd.Example)
+
+	// created by annotateSpec thus valid
+	if annotation, ok := cmd.Annotations[completionAnnotation]; ok {
+		yaml.Unmarshal([]byte(annotation), &c.Completion)
+	}
+	if annotation, ok := cmd.Annotations[runAnnotation]; ok {
+		yaml.Unmarshal([]byte(annotation), &c.Run)
+	}
+	if annotation, ok := cmd.Annotations[argsAnnotation]; ok {
+		yaml.Unmarshal([]byte(annotation), &c.Args)
+	}
+
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if isDefaultHelpFlag(cmd, f) {
+			return
+		}
+
+		c.AddFlag(fromPflag(f, cmd.PersistentFlags().Lookup(f.Name) != nil))
+
+		if action := fromAnnotations(f.Annotations); len(action) > 0 {
+			if c.Completion.Flag == nil {
+				c.Completion.Flag = make(map[string]command.Action)
+			}
+			if name := fromPflag(f, false).Name(); len(c.Completion.Flag[name].Values) == 0 {
+				c.Completion.Flag[name] = command.Action{Values: action}
+			}
+		}
+
+		if requires := f.Annotations[requiresAnnotation]; len(requires) > 0 {
+			if c.Requires == nil {
+				c.Requires = make(map[string][]string)
+			}
+			c.Requires[f.Name] = requires
+		}
+	})
+
+	switch {
+	case cmd.DisableFlagParsing && c.Run != "" && len(c.Flags) == 0 && len(c.PersistentFlags) == 0: // implicitly disabled by run
+	case cmd.DisableFlagParsing:
+		c.Parsing = command.DISABLED
+	case !(flagSet{cmd.Flags()}).IsInterspersed():
+		c.Parsing = command.NON_INTERSPERSED
+	}
+
+	for annotation, groups := range map[string]*[][]string{
+		exclusiveAnnotation:        &c.ExclusiveFlags,
+		requiredTogetherAnnotation: &c.RequiredTogether,
+		oneRequiredAnnotation:      &c.OneRequired,
+	} {
+		if g := flagGroups(cmd, annotation); len(g) > 0 {
+			*groups = g
+		}
+	}
+
+	if len(cmd.ValidArgs) > 0 && len(c.Completion.PositionalAny) == 0 {
+		c.Completion.PositionalAny = cmd.ValidArgs
+	}
+
+	for _, subcmd := range cmd.Commands() {
+		if subcmd.Name() != "_carapace" {
+			c.Commands = append(c.Commands, FromCobra(subcmd))
+		}
+	}
+	return c
+}
+
+func fromPflag(f *pflag.Flag, persistent bool) command.Flag {
+	ff := pflagfork.Flag{Flag: f}
+	flag := command.Flag{
+		Longhand:    f.Name,
+		Shorthand:   f.Shorthand,
+		Description: f.Usage,
+		Repeatable:  ff.IsRepeatable(),
+		Optarg:      ff.TakesValue() && ff.IsOptarg(),
+		Value:       ff.TakesValue(),
+		Hidden:      f.Hidden && f.Deprecated == "", // deprecated flags are implicitly hidden
+		Required:    ff.Required(),
+		Persistent:  persistent,
+		Nargs:       ff.Nargs(),
+
+		Deprecated:          f.Deprecated,
+		ShorthandDeprecated: f.ShorthandDeprecated,
+	}
+
+	switch ff.Mode() {
+	case pflagfork.ShorthandOnly:
+		flag.Longhand = ""
+	case pflagfork.NameAsShorthand:
+		flag.NameAsShorthand = true
+	}
+
+	if env := f.Annotations[envAnnotation]; len(env) == 1 {
+		flag.Env = env[0]
+		flag.Description = strings.TrimSuffix(flag.Description, fmt.Sprintf(" [$%v]", flag.Env))
+	}
+
+	if flag.Value {
+		value := f.Value
+		if enum, ok := value.(enumValue); ok {
+			flag.Type = "enum"
+			flag.Enum = enum.enum
+			value = enum.Value
+		}
+		if flag.Type == "" {
+			flag.Type = map[string]string{
+				"int":           "int",
+				"intSlice":      "int",
+				"float64":       "float",
+				"float64Slice":  "float",
+				"duration":      "duration",
+				"durationSlice": "duration",
+			}[value.Type()]
+		}
+		flag.Default = flagDefault(f, value)
+	}
+	return flag
+}
+
+// flagDefault returns the default of given flag in the format of the spec (empty for the zero value).
+func flagDefault(f *pflag.Flag, value pflag.Value) string {
+	if slice, ok := value.(pflag.SliceValue); ok {
+		if len(slice.GetSlice()) == 0 {
+			return ""
+		}
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(slice.GetSlice())
+		w.Flush()
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+
+	switch f.DefValue {
+	case "", "0", "0s":
+		return ""
+	default:
+		return f.DefValue
+	}
+}
+
+// parseExamples parses examples formatted by formatExamples.
+func parseExamples(s string) map[string]string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	examples := make(map[string]string)
+	for _, block := range strings.Split(s, "\n\n") {
+		lines := strings.Split(block, "\n")
+		for index := range lines {
+			lines[index] = strings.TrimSpace(lines[index])
+		}
+		if description, ok := strings.CutPrefix(lines[0], "# "); ok && len(lines) > 1 {
+			examples[strings.Join(lines[1:], "\n")] = description
+		} else {
+			examples[strings.Join(lines, "\n")] = ""
+		}
+	}
+	return examples
+}
+
+func fromAnnotations(annotations map[string][]string) []string {
+	if extensions, ok := annotations[cobra.BashCompFilenameExt]; ok {
+		if len(extensions) == 0 {
+			return []string{"$files"}
+		}
+		suffixes := make([]string, 0, len(extensions))
+		for _, extension := range extensions {
+			suffixes = append(suffixes, "."+strings.TrimPrefix(extension, "."))
+		}
+		return []string{"$files([" + strings.Join(suffixes, ", ") + "])"}
+	}
+
+	if dirs, ok := annotations[cobra.BashCompSubdirsInDir]; ok {
+		if len(dirs) == 0 {
+			return []string{"$directories"}
+		}
+		return []string{"$directories", "$chdir(" + dirs[0] + ")"}
+	}
+	return nil
+}
+
+func isDefaultHelpFlag(cmd *cobra.Command, f *pflag.Flag) bool {
+	return f.Name == "help" && f.Usage == "help for "+cmd.Name()
+}
@@ -0,0 +1,191 @@
+package spec
+
+import (
+	"os"
+	"testing"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFromCobra(t *testing.T) {
+	rootCmd := &cobra.Command{
+		Use:   "root",
+		Short: "root command",
+	}
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "verbose output")
+	rootCmd.AddGroup(&cobra.Group{ID: "main", Title: "Main Commands"})
+
+	subCmd := &cobra.Command{
+		Use:       "sub [file]",
+		Short:     "subcommand",
+		Long:      "longer description",
+		Aliases:   []string{"s"},
+		GroupID:   "main",
+		ValidArgs: []string{"one", "two\tdescribed"},
+	}
+	subCmd.Flags().StringP("config", "c", "", "config file")
+	subCmd.Flags().StringSlice("tag", nil, "tags")
+	subCmd.Flags().String("optarg", "", "optional argument")
+	subCmd.Flags().Lookup("optarg").NoOptDefVal = " "
+	subCmd.Flags().Bool("add", false, "add")
+	subCmd.Flags().Bool("delete", false, "delete")
+	subCmd.Flags().Bool("secret", false, "hidden")
+	subCmd.Flags().Lookup("secret").Hidden = true
+	subCmd.MarkFlagRequired("config")
+	subCmd.MarkFlagFilename("config", "yaml", "yml")
+	subCmd.MarkFlagsMutuallyExclusive("add", "delete")
+	subCmd.MarkFlagsRequiredTogether("config", "tag")
+	subCmd.MarkFlagsOneRequired("add", "delete")
+	rootCmd.AddCommand(subCmd)
+
+	hiddenCmd := &cobra.Command{
+		Use:                "hidden",
+		Hidden:             true,
+		DisableFlagParsing: true,
+	}
+	rootCmd.AddCommand(hiddenCmd)
+
+	m, err := yaml.Marshal(FromCobra(rootCmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `name: root
+description: root command
+persistentflags:
+    -v, --verbose: verbose output
+commands:
+    - name: hidden
+      hidden: true
+      parsing: disabled
+    - name: sub [file]
+      aliases: [s]
+      description: subcommand
+      group: main
+      flags:
+        --add: add
+        --delete: delete
+        --optarg?: optional argument
+        --secret&: hidden
+        --tag=*: tags
+        -c, --config=!: config file
+      exclusiveflags:
+        - [add, delete]
+      requiredtogether:
+        - [config, tag]
+      onerequired:
+        - [add, delete]
+      completion:
+        flag:
+            config: ["$files([.yaml, .yml])"]
+        positionalany: [one, "two\tdescribed"]
+      documentation:
+        command: longer description
+`, string(m))
+}
+
+func TestFromCobraDeprecated(t *testing.T) {
+	content, err := os.ReadFile("example/deprecated.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c Command
+	if err := yaml.Unmarshal(content, &c); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := c.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "use --new-name instead", cmd.Flag("old-name").Deprecated)
+	assert.Equal(t, "use --verbose instead", cmd.Flag("verbose").ShorthandDeprecated)
+	if subcmd, _, err := cmd.Find([]string{"legacy"}); err != nil || subcmd.Deprecated != "use current instead" {
+		t.Errorf("expected deprecated subcommand: %v", err)
+	}
+
+	m, err := yaml.Marshal(FromCobra(cmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `name: deprecated
+flags:
+    --old-name=:
+        description: name to use
+        deprecated: use --new-name instead
+    -n, --new-name=: name to use
+    -v, --verbose:
+        description: verbose output
+        shorthanddeprecated: use --verbose instead
+commands:
+    - name: current
+      description: current subcommand
+    - name: legacy
+      description: legacy subcommand
+      deprecated: use current instead
+`, string(m))
+}
+
+func TestFromCobraCompletion(t *testing.T) {
+	c := Command{
+		Name:  "completion",
+		Flags: command.FlagSet{"config": {Longhand: "config", Value: true}},
+		Completion: command.Completion{
+			Flag:          map[string]command.Action{"config": {Values: []string{"$files([.yaml])"}}},
+			Positional:    []command.Action{{Values: []string{"$directories"}}},
+			PositionalAny: []string{"one", "two"},
+		},
+	}
+	cmd, err := c.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, c.Completion, FromCobra(cmd).Completion)
+}
+
+func TestFromCobraRoundTrip(t *testing.T) {
+	spec := `name: roundtrip
+description: round trip
+flags:
+    --color=:
+        description: colorize
+        type: enum
+        default: auto
+        enum: [auto, always, never]
+    --count=:
+        description: count
+        type: int
+        default: "3"
+    --tags=*:
+        description: tags
+        default: a,"b,c"
+    --timeout=:
+        description: timeout
+        type: duration
+        env: ROUNDTRIP_TIMEOUT
+run: [echo, one]
+args:
+    max: 2
+examples:
+    roundtrip --count 2: count to two
+    roundtrip --tags a: ""
+`
+	var c Command
+	if err := yaml.Unmarshal([]byte(spec), &c); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := c.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := yaml.Marshal(FromCobra(cmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, spec, string(m))
+}
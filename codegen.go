@@ -3,29 +3,87 @@ package spec
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"go/format"
+	"io/fs"
+	"maps"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/carapace-sh/carapace-spec/internal/pflagfork"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
 type codegenCmd struct {
-	cmd *cobra.Command
+	cmd  *cobra.Command
+	spec *Command // optional spec the command was created from
 }
 
-func (s codegenCmd) formatHeader() string {
-	return `package cmd
-import (
-	"github.com/carapace-sh/carapace"
-	"github.com/spf13/cobra"
-)
-`
+// imports returns the packages used by the generated code of the command.
+func (s codegenCmd) imports() map[string]bool {
+	imports := map[string]bool{
+		carapaceImport:           true,
+		"github.com/spf13/cobra": true,
+	}
+	s.cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if len(f.Annotations[requiresAnnotation]) > 0 {
+			imports["errors"] = true
+		}
+		if f.Value.Type() == "durationSlice" && f.Value.String() != "[]" {
+			imports["time"] = true
+		}
+		if _, ok := f.Value.(enumValue); ok {
+			imports[specImport] = true
+		}
+	})
+	if s.bindsEnv() {
+		imports[specImport] = true
+	}
+	if s.runs() || s.validatesArgs() {
+		imports[specImport] = true
+	}
+	return imports
+}
+
+// generatedHeader marks generated files (see https://go.dev/s/generatedcode).
+const generatedHeader = "// Code generated by carapace-spec. DO NOT EDIT."
+
+func formatHeader(pkg string, imports map[string]bool) string {
+	stdlib := make([]string, 0) // standard library
+	thirdparty := make([]string, 0)
+	for i := range imports {
+		if strings.Contains(strings.SplitN(i, "/", 2)[0], ".") {
+			thirdparty = append(thirdparty, i)
+		} else {
+			stdlib = append(stdlib, i)
+		}
+	}
+	slices.Sort(stdlib)
+	slices.Sort(thirdparty)
+
+	snippet := fmt.Sprintf("%v\n\npackage %v\nimport (\n", generatedHeader, pkg)
+	for _, i := range stdlib {
+		snippet += fmt.Sprintf("\t%#v\n", i)
+	}
+	if len(stdlib) > 0 {
+		snippet += "\n"
+	}
+	for _, i := range thirdparty {
+		if i == specImport {
+			snippet += fmt.Sprintf("\tspec %#v\n", i)
+			continue
+		}
+		snippet += fmt.Sprintf("\t%#v\n", i)
+	}
+	return snippet + ")\n"
 }
 
 func (s codegenCmd) formatGroups() string {
@@ -48,9 +106,11 @@ func (s codegenCmd) formatCommand() string {
 	GroupID: %#v,
 	Aliases: []string{"%v"},
 	Hidden:  %v,
+	Deprecated: %#v,
+	DisableFlagParsing: %v,
 	Run:     func(cmd *cobra.Command, args []string) {},
 }
-`, cmdVarName(s.cmd), strings.SplitN(s.cmd.Use, "\n", 2)[0], s.cmd.Short, s.cmd.GroupID, strings.Join(s.cmd.Aliases, `", "`), s.cmd.Hidden)
+`, cmdVarName(s.cmd), strings.SplitN(s.cmd.Use, "\n", 2)[0], s.cmd.Short, s.cmd.GroupID, strings.Join(s.cmd.Aliases, `", "`), s.cmd.Hidden, s.cmd.Deprecated, s.cmd.DisableFlagParsing)
 
 	if s.cmd.GroupID == "" {
 		re := regexp.MustCompile("(?m)\n\tGroupID:.*$")
@@ -68,9 +128,109 @@ func (s codegenCmd) formatCommand() string {
 		snippet = re.ReplaceAllString(snippet, "")
 	}
 
+	if s.cmd.Deprecated == "" {
+		re := regexp.MustCompile("(?m)\n\tDeprecated:.*$")
+		snippet = re.ReplaceAllString(snippet, "")
+	}
+
+	if !s.cmd.DisableFlagParsing {
+		re := regexp.MustCompile("(?m)\n\tDisableFlagParsing:.*$")
+		snippet = re.ReplaceAllString(snippet, "")
+	}
+
+	if s.validatesArgs() {
+		re := regexp.MustCompile("(?m)^\tRun:")
+		snippet = re.ReplaceAllLiteralString(snippet, fmt.Sprintf("\tArgs: %v,\n\tRun:", s.spec.formatArgs()))
+	}
+
+	if s.runs() {
+		re := regexp.MustCompile("(?m)^\tRun:.*$")
+		snippet = re.ReplaceAllString(snippet, fmt.Sprintf("\tRunE: spec.RunE(%vRun),", cmdVarName(s.cmd)))
+		snippet += fmt.Sprintf("\n// %vRun is executed by %vCmd.\nconst %vRun = %v\n", cmdVarName(s.cmd), cmdVarName(s.cmd), cmdVarName(s.cmd), formatRun(string(s.spec.Run)))
+	}
+
 	return snippet
 }
 
+// runs checks whether the command executes the `run` field of its spec.
+func (s codegenCmd) runs() bool {
+	return s.spec != nil && s.spec.Run != "" && run(s.spec.Run).Parse() != nil
+}
+
+// validatesArgs checks whether the command validates its positional arguments.
+func (s codegenCmd) validatesArgs() bool {
+	return s.spec != nil && !s.spec.Args.IsZero()
+}
+
+// formatRun formats given run as raw string literal if possible.
+func formatRun(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+func (s codegenCmd) formatFlagGroups() string {
+	snippet := ""
+	for _, group := range flagGroups(s.cmd, exclusiveAnnotation) {
+		snippet += fmt.Sprintf("%vCmd.MarkFlagsMutuallyExclusive(\"%v\")\n", cmdVarName(s.cmd), strings.Join(group, `", "`))
+	}
+	for _, group := range flagGroups(s.cmd, requiredTogetherAnnotation) {
+		snippet += fmt.Sprintf("%vCmd.MarkFlagsRequiredTogether(\"%v\")\n", cmdVarName(s.cmd), strings.Join(group, `", "`))
+	}
+	for _, group := range flagGroups(s.cmd, oneRequiredAnnotation) {
+		snippet += fmt.Sprintf("%vCmd.MarkFlagsOneRequired(\"%v\")\n", cmdVarName(s.cmd), strings.Join(group, `", "`))
+	}
+
+	preRun := ""
+	if s.bindsEnv() {
+		preRun += "if err := spec.SetFlagsFromEnv(cmd); err != nil {\nreturn err\n}\n"
+	}
+	s.cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		for _, name := range f.Annotations[requiresAnnotation] {
+			preRun += fmt.Sprintf("if cmd.Flags().Changed(%#v) && !cmd.Flags().Changed(%#v) {\nreturn errors.New(%#v)\n}\n", f.Name, name, fmt.Sprintf("flag --%v requires --%v", f.Name, name))
+		}
+	})
+	if preRun != "" {
+		snippet += fmt.Sprintf("%vCmd.PreRunE = func(cmd *cobra.Command, args []string) error {\n%vreturn nil\n}\n", cmdVarName(s.cmd), preRun)
+	}
+	return snippet
+}
+
+// bindsEnv checks whether any flag of the command (including inherited ones) is bound to an environment variable.
+func (s codegenCmd) bindsEnv() bool {
+	binds := false
+	for _, fs := range []*pflag.FlagSet{s.cmd.LocalFlags(), s.cmd.InheritedFlags()} {
+		fs.VisitAll(func(f *pflag.Flag) {
+			binds = binds || len(f.Annotations[envAnnotation]) > 0
+		})
+	}
+	return binds
+}
+
+// formatCompletion formats the completions of the spec along with the imports it needs.
+func (s codegenCmd) formatCompletion() (string, map[string]bool) {
+	imports := make(map[string]bool)
+	if s.spec == nil {
+		return "", imports
+	}
+	return codegenCompletion{cmdVarName(s.cmd), imports}.format(command.Command(*s.spec)), imports
+}
+
+// subcommandSpec returns the spec of given subcommand.
+func (s codegenCmd) subcommandSpec(subcmd *cobra.Command) *Command {
+	if s.spec == nil {
+		return nil
+	}
+	for _, c := range s.spec.Commands {
+		if fields := strings.Fields(c.Name); len(fields) > 0 && fields[0] == subcmd.Name() {
+			subspec := Command(c)
+			return &subspec
+		}
+	}
+	return nil
+}
+
 func (s codegenCmd) formatExecute() string {
 	if s.cmd.HasParent() {
 		return ""
@@ -80,31 +240,180 @@ func (s codegenCmd) formatExecute() string {
 }
 `
 }
+
+// CodegenOptions configures the generated code.
+type CodegenOptions struct {
+	Package    string // package name (default: cmd)
+	SingleFile bool   // generate a single file instead of one per command
+}
+
+// Codegen generates code for given command into a temporary directory and prints the filenames.
+//
+// Deprecated: use GenerateCode and WriteCode instead.
 func Codegen(cmd *cobra.Command) error {
+	files, err := GenerateCode(cmd, CodegenOptions{})
+	if err != nil {
+		return err
+	}
+	return writeTempCode(files)
+}
+
+// writeTempCode writes given files into a temporary directory and prints the filenames to stderr (as Codegen always did).
+func writeTempCode(files map[string][]byte) error {
 	dir, err := os.MkdirTemp(os.TempDir(), "carapace-codegen-")
 	if err != nil {
 		return err
 	}
 
-	return codegen(cmd, dir)
+	if err := WriteCode(dir, files); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		fmt.Fprintln(os.Stderr, filepath.Join(dir, name))
+	}
+	return nil
+}
+
+// GenerateCode generates code for given command and its subcommands (filename -> content).
+func GenerateCode(cmd *cobra.Command, opts CodegenOptions) (map[string][]byte, error) {
+	return generateCode(cmd, nil, opts)
 }
 
-func codegen(cmd *cobra.Command, tmpDir string) error {
+func generateCode(cmd *cobra.Command, spec *Command, opts CodegenOptions) (map[string][]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "cmd"
+	}
+
+	type generated struct {
+		filename string
+		imports  map[string]bool
+		body     string
+	}
+	commands := make([]generated, 0)
+	var walk func(s codegenCmd)
+	walk = func(s codegenCmd) {
+		completion, imports := s.formatCompletion()
+		maps.Copy(imports, s.imports())
+		commands = append(commands, generated{codegenFilename(cmdVarName(s.cmd)), imports, s.formatBody(completion)})
+
+		for _, subcmd := range s.cmd.Commands() {
+			if subcmd.Name() != "_carapace" {
+				walk(codegenCmd{subcmd, s.subcommandSpec(subcmd)})
+			}
+		}
+	}
+	walk(codegenCmd{cmd, spec})
+
+	if opts.SingleFile {
+		imports := make(map[string]bool)
+		body := ""
+		for _, c := range commands {
+			maps.Copy(imports, c.imports)
+			body += c.body + "\n"
+		}
+		commands = []generated{{commands[0].filename, imports, body}}
+	}
+
+	files := make(map[string][]byte)
+	for _, c := range commands {
+		if _, ok := files[c.filename]; ok {
+			return nil, fmt.Errorf("duplicate filename: %v", c.filename)
+		}
+		formatted, err := formatSource(c.filename, formatHeader(opts.Package, c.imports)+"\n"+c.body)
+		if err != nil {
+			return nil, err
+		}
+		files[c.filename] = formatted
+	}
+	return files, nil
+}
+
+// WriteCode writes generated files into given directory.
+// Previously generated files which are no longer part of it (e.g. of removed subcommands) are removed.
+func WriteCode(dir string, files map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	orphaned, err := orphanedCode(dir, files)
+	if err != nil {
+		return err
+	}
+	for _, name := range orphaned {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if err := os.WriteFile(filepath.Join(dir, name), files[name], 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orphanedCode returns the generated files in given directory which are not part of given files.
+func orphanedCode(dir string, files map[string][]byte) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	orphaned := make([]string, 0)
+	for _, entry := range entries {
+		if _, ok := files[entry.Name()]; ok || entry.IsDir() || filepath.Ext(entry.Name()) != ".go" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(content, []byte(generatedHeader+"\n")) {
+			orphaned = append(orphaned, entry.Name())
+		}
+	}
+	return orphaned, nil
+}
+
+// CheckCode verifies generated files in given directory are up to date (including no orphaned ones being left).
+func CheckCode(dir string, files map[string][]byte) error {
+	stale, err := orphanedCode(dir, files)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			stale = append(stale, name)
+		case err != nil:
+			return err
+		case !bytes.Equal(content, files[name]):
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		slices.Sort(stale)
+		return fmt.Errorf("stale generated code in %v: %v", dir, strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+func (s codegenCmd) formatBody(completion string) string {
+	cmd := s.cmd
 	out := &bytes.Buffer{}
-	fmt.Fprintln(out, codegenCmd{cmd}.formatHeader())
-	fmt.Fprintln(out, codegenCmd{cmd}.formatCommand())
-	fmt.Fprintln(out, codegenCmd{cmd}.formatExecute())
+	fmt.Fprintln(out, s.formatCommand())
+	fmt.Fprintln(out, s.formatExecute())
 
 	fmt.Fprintf(out, `func init() {
 	carapace.Gen(%vCmd).Standalone()
 %v
-`, cmdVarName(cmd), codegenCmd{cmd}.formatGroups())
+`, cmdVarName(cmd), s.formatGroups())
 
 	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
-		if f.Deprecated != "" {
-			return
-		}
-
 		persistentPrefix := ""
 		if cmd.PersistentFlags().Lookup(f.Name) != nil {
 			persistentPrefix = "Persistent"
@@ -130,58 +439,91 @@ func codegen(cmd *cobra.Command, tmpDir string) error {
 	})
 
 	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
-		if f.Deprecated != "" {
-			return
+		persistentPrefix := ""
+		if cmd.PersistentFlags().Lookup(f.Name) != nil {
+			persistentPrefix = "Persistent"
+		}
+
+		if annotation := f.Annotations[envAnnotation]; len(annotation) == 1 {
+			fmt.Fprintf(out, `    spec.BindEnv(%vCmd.Flag("%v"), %#v)`+"\n", cmdVarName(cmd), f.Name, annotation[0])
+		}
+
+		if enum, ok := f.Value.(enumValue); ok {
+			fmt.Fprintf(out, `    %vCmd.Flag("%v").Value = spec.EnumValue(%vCmd.Flag("%v").Value, %v)`+"\n", cmdVarName(cmd), f.Name, cmdVarName(cmd), f.Name, quoteAll(enum.enum))
 		}
 
 		if f.Value.Type() != "bool" && f.Value.Type() != "count" && f.NoOptDefVal != "" {
 			fmt.Fprintf(out, `    %vCmd.Flag("%v").NoOptDefVal = "%v"`+"\n", cmdVarName(cmd), f.Name, f.NoOptDefVal)
 		}
 
-		if f.Hidden {
+		if f.Hidden && f.Deprecated == "" { // deprecated flags are implicitly hidden
 			fmt.Fprintf(out, `    %vCmd.Flag("%v").Hidden = true`+"\n", cmdVarName(cmd), f.Name)
 		}
 
+		if f.Deprecated != "" {
+			fmt.Fprintf(out, `    %vCmd.%vFlags().MarkDeprecated(%#v, %#v)`+"\n", cmdVarName(cmd), persistentPrefix, f.Name, f.Deprecated)
+		}
+
+		if f.ShorthandDeprecated != "" {
+			fmt.Fprintf(out, `    %vCmd.%vFlags().MarkShorthandDeprecated(%#v, %#v)`+"\n", cmdVarName(cmd), persistentPrefix, f.Name, f.ShorthandDeprecated)
+		}
+
 		if annotation := f.Annotations[cobra.BashCompOneRequiredFlag]; len(annotation) == 1 && annotation[0] == "true" {
 			fmt.Fprintf(out, `    %vCmd.MarkFlagRequired("%v")`+"\n", cmdVarName(cmd), f.Name)
 		}
 	})
 
+	fmt.Fprint(out, s.formatFlagGroups())
+	fmt.Fprint(out, completion)
+
 	if cmd.HasParent() {
 		fmt.Fprintf(out, `	%vCmd.AddCommand(%vCmd)`+"\n", cmdVarName(cmd.Parent()), cmdVarName(cmd))
 	}
 
 	fmt.Fprintln(out, "}")
+	return out.String()
+}
 
-	filename := fmt.Sprintf(`%v/%v.go`, tmpDir, cmdVarName(cmd))
-	filename = strings.NewReplacer(
-		// TODO goos/goarch suffixes
-		"_test.go", "_test_.go",
-	).Replace(filename)
-
-	println(filename)
-	formatted, err := format.Source(out.Bytes())
+// formatSource formats given source code and highlights the offending line on error.
+func formatSource(filename, source string) ([]byte, error) {
+	formatted, err := format.Source([]byte(source))
 	if err != nil {
-		unformatted := strings.Split(out.String(), "\n")
-		if line, err := strconv.Atoi(strings.SplitN(err.Error(), ":", 2)[0]); err == nil {
-			unformatted[line-1] = "\033[31m" + unformatted[line-1] + "\033[2;37m"
+		lines := strings.Split(source, "\n")
+		if line, atoiErr := strconv.Atoi(strings.SplitN(err.Error(), ":", 2)[0]); atoiErr == nil && line > 0 && line <= len(lines) {
+			return nil, fmt.Errorf("%v: %w: %#v", filename, err, lines[line-1])
 		}
-		println("\033[2;37m" + strings.Join(unformatted, "\n") + "\033[0m")
-		return err
+		return nil, fmt.Errorf("%v: %w", filename, err)
 	}
+	return formatted, nil
+}
 
-	os.WriteFile(filename, formatted, 0644)
-
-	for _, subcmd := range cmd.Commands() {
-		if subcmd.Deprecated == "" && subcmd.Name() != "_carapace" {
-			if err := codegen(subcmd, tmpDir); err != nil {
-				return err
-			}
-		}
+// codegenFilename returns the filename for given command variable name.
+//
+// Names ignored or constrained by the go tool (`_*`, `*_test`, `*_<GOOS>`, `*_<GOARCH>`) are escaped.
+func codegenFilename(name string) string {
+	if strings.HasPrefix(name, "_") {
+		name = "cmd" + name
 	}
-	return nil
+	splitted := strings.Split(name, "_")
+	if last := splitted[len(splitted)-1]; last == "test" || slices.Contains(knownOS, last) || slices.Contains(knownArch, last) {
+		name += "_"
+	}
+	return name + ".go"
 }
 
+// known GOOS and GOARCH values (see `go/build/syslist.go`)
+var (
+	knownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js", "linux",
+		"nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+	}
+	knownArch = []string{
+		"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64", "mips", "mipsle",
+		"mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le", "riscv", "riscv64",
+		"s390", "s390x", "sparc", "sparc64", "wasm",
+	}
+)
+
 func formatUsage(usage string) string {
 	return fmt.Sprintf("%q", strings.Split(usage, "\n")[0])
 }
@@ -282,14 +624,29 @@ func flagValue(f *pflag.Flag) string {
 			}
 			return fmt.Sprintf(`[]string{%v}`, formatted)
 		}
+
+		if f.Value.Type() == "durationSlice" {
+			if f.Value.String() == "[]" {
+				return "nil"
+			}
+
+			durations := make([]string, 0)
+			for _, s := range strings.Split(f.Value.String()[1:len(f.Value.String())-1], ",") {
+				d, _ := time.ParseDuration(s)
+				durations = append(durations, strconv.FormatInt(int64(d), 10))
+			}
+			return fmt.Sprintf(`[]time.Duration{%v}`, strings.Join(durations, ", "))
+		}
 		return fmt.Sprintf(`[]%v{%v}`, strings.TrimSuffix(strings.TrimSuffix(f.Value.Type(), "Slice"), "Array"), f.Value.String()[1:len(f.Value.String())-1])
 	}
 
 	switch f.Value.Type() {
 	case "string":
-		return fmt.Sprintf(`"%v"`, f.Value.String())
+		return strconv.Quote(f.Value.String())
+	case "duration":
+		d, _ := time.ParseDuration(f.Value.String())
+		return strconv.FormatInt(int64(d), 10) // untyped constant
 	case
-		"duration",
 		"float32",
 		"float64",
 		"int",
@@ -302,7 +659,7 @@ func flagValue(f *pflag.Flag) string {
 		"uint32",
 		"uint64",
 		"uint8":
-		return "0"
+		return f.Value.String()
 	case
 		"bool",
 		"boolSlice",
@@ -0,0 +1,374 @@
+package spec
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"gopkg.in/yaml.v3"
+)
+
+// import paths of packages used by generated completions
+const (
+	carapaceImport = "github.com/carapace-sh/carapace"
+	keyImport      = "github.com/carapace-sh/carapace/pkg/cache/key"
+	specImport     = "github.com/carapace-sh/carapace-spec"
+	traverseImport = "github.com/carapace-sh/carapace/pkg/traverse"
+)
+
+// codegenCompletion translates completions of a spec into carapace Go code.
+type codegenCompletion struct {
+	cmdVar  string          // variable name of the command
+	imports map[string]bool // packages used by the generated code
+}
+
+func (g codegenCompletion) format(c command.Command) string {
+	snippet := ""
+
+	flagCompletions := make(map[string]string)
+	for _, flagSet := range []command.FlagSet{c.Flags, c.PersistentFlags} {
+		for _, flag := range flagSet {
+			if len(flag.Enum) == 0 {
+				continue
+			}
+			action := fmt.Sprintf("carapace.ActionValues(%v)", quoteAll(flag.Enum))
+			if flag.Repeatable {
+				action += `.UniqueList(",")`
+			}
+			flagCompletions[flag.Name()] = action // overridden by explicit completion
+		}
+	}
+	for name, a := range c.Completion.Flag {
+		flagCompletions[name] = g.action(a)
+	}
+	if len(flagCompletions) > 0 {
+		snippet += fmt.Sprintf("carapace.Gen(%vCmd).FlagCompletion(carapace.ActionMap{\n", g.cmdVar)
+		for _, name := range slices.Sorted(maps.Keys(flagCompletions)) {
+			snippet += fmt.Sprintf("%#v: %v,\n", name, flagCompletions[name])
+		}
+		snippet += "})\n"
+	}
+
+	if Command(c).completesAlias() && run(c.Run).Parse() != nil {
+		g.imports[specImport] = true
+		snippet += fmt.Sprintf("carapace.Gen(%vCmd).PositionalAnyCompletion(spec.ActionRun(%vRun))\n", g.cmdVar, g.cmdVar)
+	}
+
+	if len(c.Completion.Positional) > 0 {
+		snippet += fmt.Sprintf("carapace.Gen(%vCmd).PositionalCompletion(\n", g.cmdVar)
+		for _, a := range c.Completion.Positional {
+			snippet += g.action(a) + ",\n"
+		}
+		snippet += ")\n"
+	}
+
+	if len(c.Completion.PositionalAny) > 0 {
+		snippet += fmt.Sprintf("carapace.Gen(%vCmd).PositionalAnyCompletion(\n%v,\n)\n", g.cmdVar, g.values(c.Completion.PositionalAny))
+	}
+
+	if len(c.Completion.Dash) > 0 {
+		snippet += fmt.Sprintf("carapace.Gen(%vCmd).DashCompletion(\n", g.cmdVar)
+		for _, values := range c.Completion.Dash {
+			snippet += g.values(values) + ",\n"
+		}
+		snippet += ")\n"
+	}
+
+	if len(c.Completion.DashAny) > 0 {
+		snippet += fmt.Sprintf("carapace.Gen(%vCmd).DashAnyCompletion(\n%v,\n)\n", g.cmdVar, g.values(c.Completion.DashAny))
+	}
+	return snippet
+}
+
+// action translates given action.
+//
+// Conditions are translated to a callback matching flags with spec.FlagMatches.
+func (g codegenCompletion) action(a command.Action) string {
+	if !a.IsConditional() {
+		return g.values(a.Values)
+	}
+	if len(a.When) == 0 {
+		return g.values(a.Else)
+	}
+	g.imports[specImport] = true
+
+	snippet := "carapace.ActionCallback(func(c carapace.Context) carapace.Action {\nswitch {\n"
+	for _, condition := range a.When {
+		checks := make([]string, 0)
+		for _, name := range slices.Sorted(maps.Keys(condition.Flag)) {
+			checks = append(checks, fmt.Sprintf("spec.FlagMatches(%vCmd.Flag(%#v), %#v, c.LookupEnv)", g.cmdVar, name, condition.Flag[name]))
+		}
+		for _, index := range slices.Sorted(maps.Keys(condition.Arg)) {
+			checks = append(checks, fmt.Sprintf("len(c.Args) > %v && c.Args[%v] == %#v", index, index, condition.Arg[index]))
+		}
+		if len(checks) == 0 {
+			checks = append(checks, "true")
+		}
+		snippet += fmt.Sprintf("case %v:\nreturn %v\n", strings.Join(checks, " && "), g.values(condition.Then))
+	}
+	return snippet + fmt.Sprintf("default:\nreturn %v\n}\n})", g.values(a.Else))
+}
+
+// values translates given values and macros.
+//
+// Unknown macros fall back to `spec.ActionMacro` and values containing variables to the spec runtime.
+func (g codegenCompletion) values(values []string) string {
+	if len(values) == 0 {
+		return "carapace.ActionValues()"
+	}
+
+	static := make([][]string, 0) // value, description, style
+	actions := make([]string, 0)
+	modifiers := make([]string, 0) // generic modifiers applied to the batch
+	for _, value := range values {
+		if strings.Contains(value, "${") {
+			return g.fallback(values) // variables are only substituted by the spec runtime
+		}
+
+		splitted := strings.Split(value, " ||| ")
+		if strings.HasPrefix(splitted[0], "$") {
+			if isModifier(splitted[0]) {
+				for _, s := range splitted {
+					if _, ok := g.apply("", s); !ok {
+						return g.fallback(values)
+					}
+					modifiers = append(modifiers, s)
+				}
+				continue
+			}
+		}
+
+		if len(splitted) == 1 && !strings.HasPrefix(splitted[0], "$") {
+			static = append(static, strings.SplitN(splitted[0], "\t", 3))
+			continue
+		}
+
+		action := g.value(splitted[0])
+		for _, s := range splitted[1:] {
+			var ok bool
+			if action, ok = g.apply(action, s); !ok {
+				return g.fallback(values)
+			}
+		}
+		actions = append(actions, action)
+	}
+
+	if len(static) > 0 {
+		actions = append([]string{formatStatic(static)}, actions...)
+	}
+
+	snippet := ""
+	switch len(actions) {
+	case 0:
+		snippet = "carapace.ActionValues()"
+	case 1:
+		snippet = actions[0]
+	default:
+		snippet = fmt.Sprintf("carapace.Batch(\n%v,\n).ToA()", strings.Join(actions, ",\n"))
+	}
+	for _, s := range modifiers {
+		snippet, _ = g.apply(snippet, s) // already verified
+	}
+	return snippet
+}
+
+// fallback completes given values with the spec runtime.
+func (g codegenCompletion) fallback(values []string) string {
+	g.imports[specImport] = true
+	return fmt.Sprintf("spec.NewAction([]string{%v}).Parse(%vCmd)", quoteAll(values), g.cmdVar)
+}
+
+// value translates a static value or macro.
+func (g codegenCompletion) value(s string) string {
+	if !strings.HasPrefix(s, "$") {
+		return formatStatic([][]string{strings.SplitN(s, "\t", 3)})
+	}
+
+	name, arg, ok := splitMacro(s)
+	switch {
+	case !ok:
+	case name == "directories" && arg == "":
+		return "carapace.ActionDirectories()"
+	case name == "files" || name == "executables":
+		var args []string
+		if arg != "" {
+			if err := yaml.Unmarshal([]byte(arg), &args); err != nil {
+				break
+			}
+		}
+		return fmt.Sprintf("carapace.Action%v%v(%v)", strings.ToUpper(name[:1]), name[1:], quoteAll(args))
+	case name == "message":
+		return fmt.Sprintf("carapace.ActionMessage(%#v)", escapeFormat(arg))
+	}
+
+	g.imports[specImport] = true
+	return fmt.Sprintf("spec.ActionMacro(%#v)", s)
+}
+
+// apply applies given modifier to the translated action.
+func (g codegenCompletion) apply(action, s string) (string, bool) {
+	if name, arg, ok := splitMacro(s); ok && name == "cache" {
+		return g.cache(action, arg)
+	}
+	m, ok := g.modifier(s)
+	return action + m, ok
+}
+
+// cache translates the cache modifier (`<duration>[,<key>...]`).
+// Like the runtime it is keyed by the working directory (the action itself is identified by the call site).
+func (g codegenCompletion) cache(action, arg string) (string, bool) {
+	splitted := strings.Split(arg, ",")
+	timeout, err := time.ParseDuration(strings.TrimSpace(splitted[0]))
+	if err != nil {
+		return "", false
+	}
+
+	keys := []string{"c.Dir"}
+	for _, k := range splitted[1:] {
+		keys = append(keys, strconv.Quote(strings.TrimSpace(k)))
+	}
+	g.imports[keyImport] = true
+	return fmt.Sprintf("carapace.ActionCallback(func(c carapace.Context) carapace.Action {\nreturn %v.Cache(%v, key.String(%v)).Invoke(c).ToA()\n})", action, int64(timeout), strings.Join(keys, ", ")), true
+}
+
+// modifier translates a modifier into a method call (e.g. `.Prefix("file://")`).
+func (g codegenCompletion) modifier(s string) (string, bool) {
+	name, arg, ok := splitMacro(s)
+	if !ok {
+		return "", false
+	}
+
+	list := func() (string, bool) {
+		var args []string
+		if err := yaml.Unmarshal([]byte(arg), &args); err != nil {
+			return "", false
+		}
+		return quoteAll(args), true
+	}
+
+	switch name {
+	case "chdir":
+		if !strings.HasPrefix(arg, "$") {
+			return fmt.Sprintf(".Chdir(%#v)", arg), true
+		}
+		traverse, traverseArg, ok := splitMacro(arg)
+		if !ok {
+			return "", false
+		}
+		funcs := map[string]string{
+			"gitdir":        "GitDir",
+			"gitworktree":   "GitWorkTree",
+			"nixprofile":    "NixProfile",
+			"tempdir":       "TempDir",
+			"usercachedir":  "UserCacheDir",
+			"userconfigdir": "UserConfigDir",
+			"userhomedir":   "UserHomeDir",
+			"xdgcachehome":  "XdgCacheHome",
+			"xdgconfighome": "XdgConfigHome",
+		}
+		if f, ok := funcs[traverse]; ok && traverseArg == "" {
+			g.imports[traverseImport] = true
+			return fmt.Sprintf(".ChdirF(traverse.%v)", f), true
+		}
+		if traverse == "parent" {
+			var args []string
+			if err := yaml.Unmarshal([]byte(traverseArg), &args); err == nil {
+				g.imports[traverseImport] = true
+				return fmt.Sprintf(".ChdirF(traverse.Parent(%v))", quoteAll(args)), true
+			}
+		}
+		return "", false
+
+	case "filter", "multiparts", "retain":
+		args, ok := list()
+		if !ok {
+			return "", false
+		}
+		method := map[string]string{"filter": "Filter", "multiparts": "MultiParts", "retain": "Retain"}[name]
+		return fmt.Sprintf(".%v(%v)", method, args), true
+
+	case "filterargs", "split", "splitp":
+		method := map[string]string{"filterargs": "FilterArgs", "split": "Split", "splitp": "SplitP"}[name]
+		return fmt.Sprintf(".%v()", method), arg == ""
+
+	case "list", "prefix", "style", "suffix", "suppress", "tag", "uniquelist":
+		method := map[string]string{"list": "List", "prefix": "Prefix", "style": "Style", "suffix": "Suffix", "suppress": "Suppress", "tag": "Tag", "uniquelist": "UniqueList"}[name]
+		return fmt.Sprintf(".%v(%#v)", method, arg), true
+
+	case "nospace", "noprefix":
+		runes := make([]string, 0)
+		for _, r := range arg {
+			runes = append(runes, strconv.QuoteRune(r))
+		}
+		method := map[string]string{"nospace": "NoSpace", "noprefix": "NoPrefix"}[name]
+		return fmt.Sprintf(".%v(%v)", method, strings.Join(runes, ", ")), true
+
+	case "shift":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return "", false
+		}
+		return fmt.Sprintf(".Shift(%v)", n), true
+
+	case "usage":
+		return fmt.Sprintf(".Usage(%#v)", escapeFormat(arg)), true
+
+	default: // e.g. `$timeout` which relies on the spec runtime to kill shell macros
+		return "", false
+	}
+}
+
+// splitMacro splits a macro into its name and argument.
+func splitMacro(s string) (name, arg string, ok bool) {
+	matches := regexp.MustCompile(`^\$(?P<macro>[^(]*)(\((?P<arg>.*)\))?$`).FindStringSubmatch(s)
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[1], matches[3], true
+}
+
+// formatStatic formats static values with their optional description and style.
+func formatStatic(static [][]string) string {
+	width := 1
+	for _, value := range static {
+		width = max(width, len(value))
+	}
+
+	args := make([]string, 0)
+	for _, value := range static {
+		for i := range width {
+			if i < len(value) {
+				args = append(args, strconv.Quote(value[i]))
+			} else {
+				args = append(args, `""`)
+			}
+		}
+	}
+
+	switch width {
+	case 1:
+		return fmt.Sprintf("carapace.ActionValues(%v)", strings.Join(args, ", "))
+	case 2:
+		return fmt.Sprintf("carapace.ActionValuesDescribed(%v)", strings.Join(args, ", "))
+	default:
+		return fmt.Sprintf("carapace.ActionStyledValuesDescribed(%v)", strings.Join(args, ", "))
+	}
+}
+
+func quoteAll(s []string) string {
+	quoted := make([]string, 0, len(s))
+	for _, e := range s {
+		quoted = append(quoted, strconv.Quote(e))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// escapeFormat escapes given string for use as format string.
+func escapeFormat(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
@@ -0,0 +1,361 @@
+package spec
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCodegenValues(t *testing.T) {
+	for values, expected := range map[string]string{
+		`[]`:                                        `carapace.ActionValues()`,
+		`[one, two]`:                                `carapace.ActionValues("one", "two")`,
+		"[\"one\\tfirst\", two]":                    `carapace.ActionValuesDescribed("one", "first", "two", "")`,
+		"[\"one\\tfirst\\tblue\"]":                  `carapace.ActionStyledValuesDescribed("one", "first", "blue")`,
+		`[$files]`:                                  `carapace.ActionFiles()`,
+		`["$files([.go, go.mod])"]`:                 `carapace.ActionFiles(".go", "go.mod")`,
+		`[$directories]`:                            `carapace.ActionDirectories()`,
+		`["$message(100% done)"]`:                   `carapace.ActionMessage("100%% done")`,
+		`[$directories, "$chdir(/tmp)"]`:            `carapace.ActionDirectories().Chdir("/tmp")`,
+		`["$files ||| $chdir($gitdir)"]`:            `carapace.ActionFiles().ChdirF(traverse.GitDir)`,
+		`[one, "$list(,)"]`:                         `carapace.ActionValues("one").List(",")`,
+		`[one, "$nospace(/)", "$shift(1)"]`:         `carapace.ActionValues("one").NoSpace('/').Shift(1)`,
+		`["$multiparts([/])", a/b]`:                 `carapace.ActionValues("a/b").MultiParts("/")`,
+		`[one, "$cache(1m)"]`:                       "carapace.ActionCallback(func(c carapace.Context) carapace.Action {\nreturn carapace.ActionValues(\"one\").Cache(60000000000, key.String(c.Dir)).Invoke(c).ToA()\n})",
+		`["$files ||| $cache(1m, a) ||| $tag(f)"]`:  "carapace.ActionCallback(func(c carapace.Context) carapace.Action {\nreturn carapace.ActionFiles().Cache(60000000000, key.String(c.Dir, \"a\")).Invoke(c).ToA()\n}).Tag(\"f\")",
+		`["$(git branch)"]`:                         `spec.ActionMacro("$(git branch)")`,
+		`[one, "$(git branch) ||| $tag(branches)"]`: "carapace.Batch(\ncarapace.ActionValues(\"one\"),\nspec.ActionMacro(\"$(git branch)\").Tag(\"branches\"),\n).ToA()",
+		`["$(echo ${C_VALUE})"]`:                    `spec.NewAction([]string{"$(echo ${C_VALUE})"}).Parse(rootCmd)`,
+		`["$(sleep 10)", "$timeout(2s)"]`:           `spec.NewAction([]string{"$(sleep 10)", "$timeout(2s)"}).Parse(rootCmd)`,
+		`["$(sleep 10) ||| $timeout(2s)"]`:          `spec.NewAction([]string{"$(sleep 10) ||| $timeout(2s)"}).Parse(rootCmd)`,
+		`[one, "$unknown"]`:                         `carapace.Batch(` + "\n" + `carapace.ActionValues("one"),` + "\n" + `spec.ActionMacro("$unknown"),` + "\n" + `).ToA()`,
+	} {
+		var v []string
+		if err := yaml.Unmarshal([]byte(values), &v); err != nil {
+			t.Fatal(err)
+		}
+		if actual := (codegenCompletion{"root", make(map[string]bool)}).values(v); actual != expected {
+			t.Errorf("%v: expected %#v, got %#v", values, expected, actual)
+		}
+	}
+}
+
+func TestCodegen(t *testing.T) {
+	content, err := os.ReadFile("example/command.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd Command
+	if err := yaml.Unmarshal(content, &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cmd.GenerateCode(CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated, ok := files["completion_when.go"]
+	if !ok {
+		t.Fatal("missing completion_when.go")
+	}
+	for _, expected := range []string{
+		`"format": carapace.ActionValues("json", "yaml"),`,
+		`carapace.ActionValues("deploy", "destroy"),`,
+		`case spec.FlagMatches(completion_whenCmd.Flag("format"), "json", c.LookupEnv):`,
+		`case len(c.Args) > 0 && c.Args[0] == "deploy":`,
+		`return carapace.ActionFiles(".json")`,
+	} {
+		if !strings.Contains(string(generated), expected) {
+			t.Errorf("missing %#v in:\n%v", expected, string(generated))
+		}
+	}
+}
+
+func TestCodegenFilename(t *testing.T) {
+	for name, expected := range map[string]string{
+		"root":                "root.go",
+		"subcommand_test":     "subcommand_test_.go",
+		"subcommand_windows":  "subcommand_windows_.go",
+		"subcommand_arm64":    "subcommand_arm64_.go",
+		"subcommand_linuxish": "subcommand_linuxish.go",
+		"_hidden":             "cmd_hidden.go",
+	} {
+		if actual := codegenFilename(name); actual != expected {
+			t.Errorf("%v: expected %#v, got %#v", name, expected, actual)
+		}
+	}
+}
+
+func TestCodegenSingleFile(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte(`
+name: example
+commands:
+  - name: sub
+    flags:
+      --file=: file
+    completion:
+      flag:
+        file: ["$(ls)"]
+`), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cmd.GenerateCode(CodegenOptions{Package: "mycli", SingleFile: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected a single file, got %v", slices.Sorted(maps.Keys(files)))
+	}
+
+	generated := string(files["root.go"])
+	for _, expected := range []string{
+		"package mycli\n",
+		`spec "github.com/carapace-sh/carapace-spec"`,
+		"var rootCmd = &cobra.Command{",
+		"var subCmd = &cobra.Command{",
+		`"file": spec.ActionMacro("$(ls)"),`,
+	} {
+		if !strings.Contains(generated, expected) {
+			t.Errorf("missing %#v in:\n%v", expected, generated)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := CheckCode(dir, files); err == nil {
+		t.Error("expected missing file to be stale")
+	}
+	if err := WriteCode(dir, files); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckCode(dir, files); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCodegenRun(t *testing.T) {
+	content, err := os.ReadFile("example/run.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd Command
+	if err := yaml.Unmarshal(content, &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cmd.GenerateCode(CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for filename, expected := range map[string][]string{
+		"alias_array.go": {
+			"DisableFlagParsing: true,",
+			"RunE:               spec.RunE(alias_arrayRun),",
+			"const alias_arrayRun = `[tail, --lines, ",
+			"carapace.Gen(alias_arrayCmd).PositionalAnyCompletion(spec.ActionRun(alias_arrayRun))",
+		},
+		"script_shebang.go": {
+			"RunE:  spec.RunE(script_shebangRun),",
+			"const script_shebangRun = `#!/usr/bin/env bash -x\n",
+		},
+		"script.go": {
+			"Run:   func(cmd *cobra.Command, args []string) {},",
+		},
+	} {
+		for _, e := range expected {
+			if !strings.Contains(string(files[filename]), e) {
+				t.Errorf("missing %#v in %v:\n%v", e, filename, string(files[filename]))
+			}
+		}
+	}
+}
+
+func TestCodegenArgs(t *testing.T) {
+	content, err := os.ReadFile("example/args.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd Command
+	if err := yaml.Unmarshal(content, &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cmd.GenerateCode(CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for filename, expected := range map[string]string{
+		"none.go":  "spec.PositionalArgs(cobra.NoArgs),",
+		"exact.go": "spec.PositionalArgs(cobra.ExactArgs(2)),",
+		"range.go": "spec.PositionalArgs(cobra.RangeArgs(1, 2)),",
+		"valid.go": `spec.PositionalArgs(spec.ValidArgs([][]string{{"one", "two"}, nil}, []string{"three", "four"})),`,
+	} {
+		if !strings.Contains(string(files[filename]), expected) {
+			t.Errorf("missing %#v in %v:\n%v", expected, filename, string(files[filename]))
+		}
+	}
+}
+
+func TestCodegenDeprecated(t *testing.T) {
+	content, err := os.ReadFile("example/deprecated.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd Command
+	if err := yaml.Unmarshal(content, &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cmd.GenerateCode(CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for filename, expected := range map[string][]string{
+		"root.go": {
+			`rootCmd.Flags().String("old-name", "", "name to use")`,
+			`rootCmd.Flags().MarkDeprecated("old-name", "use --new-name instead")`,
+			`rootCmd.Flags().MarkShorthandDeprecated("verbose", "use --verbose instead")`,
+		},
+		"legacy.go": {
+			`Deprecated: "use current instead",`,
+		},
+	} {
+		for _, e := range expected {
+			if !strings.Contains(string(files[filename]), e) {
+				t.Errorf("missing %#v in %v:\n%v", e, filename, string(files[filename]))
+			}
+		}
+	}
+}
+
+func TestCodegenTyped(t *testing.T) {
+	content, err := os.ReadFile("example/command.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd Command
+	if err := yaml.Unmarshal(content, &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cmd.GenerateCode(CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`typedCmd.Flags().String("color", "auto", "flag with enum values")`,
+		`typedCmd.Flag("color").Value = spec.EnumValue(typedCmd.Flag("color").Value, "auto", "always", "never")`,
+		`spec.BindEnv(typedCmd.Flag("token"), "TYPED_TOKEN")`,
+		`if err := spec.SetFlagsFromEnv(cmd); err != nil {`,
+	} {
+		if !strings.Contains(string(files["typed.go"]), expected) {
+			t.Errorf("missing %#v in:\n%v", expected, string(files["typed.go"]))
+		}
+	}
+}
+
+func TestCodegenOrphaned(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte(`
+name: example
+commands:
+  - name: sub
+`), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cmd.GenerateCode(CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := WriteCode(dir, files); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "custom.go"), []byte("package cmd\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd.Commands = nil
+	if files, err = cmd.GenerateCode(CodegenOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fmt.Sprintf("stale generated code in %v: sub.go", dir), CheckCode(dir, files).Error())
+
+	if err := WriteCode(dir, files); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckCode(dir, files); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub.go")); !os.IsNotExist(err) {
+		t.Error("expected orphaned file to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "custom.go")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCodegenOverlay(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // linux
+	t.Setenv("HOME", dir)            // darwin
+	t.Setenv("AppData", dir)         // windows
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayDir := filepath.Join(configDir, "carapace-spec", "overlays")
+	if err := os.MkdirAll(overlayDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(overlayDir, "example.yaml"), []byte("- operation: add\n  command:\n    commands:\n      - name: extra\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "example.yaml")
+	if err := os.WriteFile(path, []byte("name: example\ncommands:\n  - name: sub\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overlaid, _, err := command.LoadWithOverlays(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Command(*overlaid).GenerateCode(CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["extra.go"]; !ok {
+		t.Fatal("expected overlay to be applied")
+	}
+
+	cmd, err := command.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, err = Command(*cmd).GenerateCode(CodegenOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"root.go", "sub.go"}, slices.Sorted(maps.Keys(files)))
+}
@@ -1,9 +1,6 @@
 package spec
 
 import (
-	"os"
-	"path/filepath"
-
 	"github.com/carapace-sh/carapace"
 	"github.com/carapace-sh/carapace-spec/pkg/command"
 	"github.com/spf13/cobra"
@@ -12,6 +9,14 @@ import (
 
 type Command command.Command
 
+func (c Command) MarshalYAML() (any, error) {
+	return command.Command(c).MarshalYAML()
+}
+
+func (c *Command) UnmarshalYAML(value *yaml.Node) error {
+	return value.Decode((*command.Command)(c))
+}
+
 func (c Command) ToCobra() *cobra.Command {
 	cmd, err := c.ToCobraE()
 	if err != nil {
@@ -30,13 +35,14 @@ func (c Command) ToCobra() *cobra.Command {
 
 func (c Command) ToCobraE() (*cobra.Command, error) {
 	cmd := &cobra.Command{
-		Use:     c.Name,
-		Aliases: c.Aliases,
-		Short:   c.Description,
-		GroupID: c.Group,
-		Args:    cobra.ArbitraryArgs,
-		Hidden:  c.Hidden,
-		Run:     func(cmd *cobra.Command, args []string) {},
+		Use:        c.Name,
+		Aliases:    c.Aliases,
+		Short:      c.Description,
+		GroupID:    c.Group,
+		Args:       cobra.ArbitraryArgs,
+		Hidden:     c.Hidden,
+		Deprecated: c.Deprecated,
+		Run:        func(cmd *cobra.Command, args []string) {},
 	}
 
 	switch c.Parsing {
@@ -51,34 +57,51 @@ func (c Command) ToCobraE() (*cobra.Command, error) {
 	for _, f := range []func(cmd *cobra.Command) error{
 		c.addFlags,
 		c.addPersistentFlags,
-		c.markFlagsExclusive,
+		c.markFlagGroups,
 		c.addRun,
+		c.addArgs,
 		c.addFlagCompletion,
 		c.addPositionalCompletion,
 		c.addPositionalAnyCompletion,
 		c.addDashCompletion,
 		c.addDashAnyCompletion,
+		c.annotateSpec,
 		c.addSubcommands,
 		c.addAliasCompletion,
+		c.addDocumentation,
 	} {
 		if err := f(cmd); err != nil {
-			return nil, err
+			return nil, command.Command(c).Locate(command.WrapError(err, ""))
 		}
 	}
 	return cmd, nil
 }
 
+// Codegen generates code for the spec into a temporary directory and prints the filenames.
+//
+// Deprecated: use GenerateCode and WriteCode instead.
 func (c Command) Codegen() error {
-	cmd, err := c.ToCobraE()
+	files, err := c.GenerateCode(CodegenOptions{})
 	if err != nil {
 		return err
 	}
-	return Codegen(cmd)
+	return writeTempCode(files)
+}
+
+// GenerateCode generates code for the spec (filename -> content).
+func (c Command) GenerateCode(opts CodegenOptions) (map[string][]byte, error) {
+	cmd, err := c.ToCobraE()
+	if err != nil {
+		return nil, err
+	}
+	return generateCode(cmd, &c, opts)
 }
 
 func (c Command) addPersistentFlags(cmd *cobra.Command) error {
 	for _, flag := range c.PersistentFlags {
-		addFlagTo(flag, cmd.PersistentFlags())
+		if err := addFlagTo(flag, cmd.PersistentFlags()); err != nil {
+			return command.WrapError(err, command.JoinPath("persistentflags", command.Key(flag.Definition())))
+		}
 		if flag.Required {
 			cmd.MarkFlagRequired(flag.Name())
 		}
@@ -88,7 +111,9 @@ func (c Command) addPersistentFlags(cmd *cobra.Command) error {
 
 func (c Command) addFlags(cmd *cobra.Command) error {
 	for _, flag := range c.Flags {
-		addFlagTo(flag, cmd.Flags())
+		if err := addFlagTo(flag, cmd.Flags()); err != nil {
+			return command.WrapError(err, command.JoinPath("flags", command.Key(flag.Definition())))
+		}
 		if flag.Required {
 			cmd.MarkFlagRequired(flag.Longhand)
 		}
@@ -96,27 +121,41 @@ func (c Command) addFlags(cmd *cobra.Command) error {
 	return nil
 }
 
-func (c Command) markFlagsExclusive(cmd *cobra.Command) error {
-	for _, e := range c.ExclusiveFlags {
-		cmd.MarkFlagsMutuallyExclusive(e...)
-	}
-	return nil
-}
-
 func (c Command) addFlagCompletion(cmd *cobra.Command) error {
 	flagCompletions := make(carapace.ActionMap)
+	for _, flagSet := range []command.FlagSet{c.Flags, c.PersistentFlags} {
+		for _, flag := range flagSet {
+			if len(flag.Enum) == 0 {
+				continue
+			}
+			action := carapace.ActionValues(flag.Enum...)
+			if flag.Repeatable {
+				action = action.UniqueList(",")
+			}
+			flagCompletions[flag.Name()] = action // overridden by explicit completion
+		}
+	}
 	for key, a := range c.Completion.Flag {
-		flagCompletions[key] = NewAction(a).Parse(cmd)
+		flagCompletions[key] = parseAction(a, cmd)
 	}
 	carapace.Gen(cmd).FlagCompletion(flagCompletions)
 	return nil
 }
 
 func (c Command) addRun(cmd *cobra.Command) error {
-	if cmd.RunE = run(c.Run).Parse(); cmd.RunE == nil {
+	runE := run(c.Run).Parse()
+	if runE == nil {
 		return nil
 	}
 
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := SetFlagsFromEnv(cmd); err != nil { // before validation of required flags
+			return err
+		}
+		return validateRequires(cmd)
+	}
+	cmd.RunE = runE
+
 	if len(c.Flags) == 0 && len(c.PersistentFlags) == 0 {
 		cmd.DisableFlagParsing = true
 	}
@@ -130,7 +169,7 @@ func (c Command) addPositionalCompletion(cmd *cobra.Command) error {
 
 	positionalCompletions := make([]carapace.Action, 0)
 	for _, pos := range c.Completion.Positional {
-		positionalCompletions = append(positionalCompletions, NewAction(pos).Parse(cmd))
+		positionalCompletions = append(positionalCompletions, parseAction(pos, cmd))
 	}
 	carapace.Gen(cmd).PositionalCompletion(positionalCompletions...)
 
@@ -166,7 +205,7 @@ func (c Command) addDashAnyCompletion(cmd *cobra.Command) error {
 
 func (c Command) addSubcommands(cmd *cobra.Command) error {
 	groups := make(map[string]bool)
-	for _, subcmd := range c.Commands {
+	for index, subcmd := range c.Commands {
 		if subcmd.Group != "" {
 			if _, exists := groups[subcmd.Group]; !exists {
 				cmd.AddGroup(&cobra.Group{ID: subcmd.Group})
@@ -175,7 +214,7 @@ func (c Command) addSubcommands(cmd *cobra.Command) error {
 		}
 		subcmdCobra, err := Command(subcmd).ToCobraE()
 		if err != nil {
-			return err
+			return command.WrapError(err, command.JoinPath("commands", index))
 		}
 		cmd.AddCommand(subcmdCobra)
 	}
@@ -183,53 +222,20 @@ func (c Command) addSubcommands(cmd *cobra.Command) error {
 }
 
 func (c Command) addAliasCompletion(cmd *cobra.Command) error { // TODO add tests for alias completion
-	if c.Run != "" && // TODO string/alias check
+	if c.completesAlias() {
+		cmd.DisableFlagParsing = true
+		carapace.Gen(cmd).PositionalAnyCompletion(ActionRun(string(c.Run)))
+	}
+	return nil
+}
+
+// completesAlias checks whether the arguments are completed by the command the spec runs.
+func (c Command) completesAlias() bool {
+	return c.Run != "" && // TODO string/alias check
 		len(c.Flags) == 0 &&
 		len(c.PersistentFlags) == 0 &&
 		len(c.Completion.Positional) == 0 &&
 		len(c.Completion.PositionalAny) == 0 &&
 		len(c.Completion.Dash) == 0 &&
-		len(c.Completion.DashAny) == 0 {
-
-		cmd.DisableFlagParsing = true
-		carapace.Gen(cmd).PositionalAnyCompletion(
-			carapace.ActionCallback(func(context carapace.Context) carapace.Action {
-				switch c.Run.Type() {
-				case "alias":
-					var mArgs []string
-					if err := yaml.Unmarshal([]byte(c.Run), &mArgs); err != nil {
-						return carapace.ActionMessage(err.Error())
-					}
-					if len(mArgs) == 0 {
-						return carapace.ActionMessage("empty alias: %#v", c.Run)
-					}
-
-					var err error
-					for index, arg := range mArgs {
-						if mArgs[index], err = context.Envsubst(arg); err != nil {
-							return carapace.ActionMessage(err.Error())
-						}
-					}
-
-					// TODO keep in sync with ActionCarapaceBin in carapace-bridge
-					carapaceCmd := "carapace"
-					if executable, err := os.Executable(); err == nil && filepath.Base(executable) == "carapace" {
-						carapaceCmd = executable // workaround for sandbox tests: directly call executable which was built with "go run"
-					}
-
-					execArgs := []string{mArgs[0], "export", mArgs[0]}
-					execArgs = append(execArgs, mArgs[1:]...)
-					execArgs = append(execArgs, context.Args...)
-					execArgs = append(execArgs, context.Value)
-					return carapace.ActionExecCommand(carapaceCmd, execArgs...)(func(output []byte) carapace.Action {
-						return carapace.ActionImport(output)
-					})
-
-				default:
-					return carapace.ActionValues()
-				}
-			}),
-		)
-	}
-	return nil
+		len(c.Completion.DashAny) == 0
 }
@@ -44,6 +44,13 @@ func TestCommand(t *testing.T) {
 		s.Run("hidden").
 			Expect(carapace.ActionValues())
 
+		s.Run("typed", "--color", "").
+			Expect(carapace.ActionValues(
+				"auto",
+				"always",
+				"never",
+			).Usage("flag with enum values"))
+
 		s.Run("hidden", "").
 			Expect(carapace.ActionValues(
 				"p1",
@@ -176,3 +183,21 @@ func TestCommand(t *testing.T) {
 				Usage("consumes multiple arguments"))
 	})
 }
+
+//go:embed example/deprecated.yaml
+var deprecatedSpec string
+
+func TestDeprecated(t *testing.T) {
+	sandboxSpec(t, deprecatedSpec)(func(s *sandbox.Sandbox) {
+		s.Run("cur").
+			Expect(carapace.ActionValuesDescribed(
+				"current", "current subcommand",
+			).Tag("other commands"))
+
+		s.Run("leg").
+			Expect(carapace.ActionValues())
+
+		s.Run("--old").
+			Expect(carapace.ActionValues())
+	})
+}
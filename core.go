@@ -1,9 +1,14 @@
 package spec
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/carapace-sh/carapace"
 	shlex "github.com/carapace-sh/carapace-shlex"
@@ -44,13 +49,37 @@ func init() {
 	addCoreMacro("zsh", MacroI(func(s string) carapace.Action { return shell("zsh", s) }))
 }
 
+// timeoutEnv is the environment variable containing the timeout of shell macros (`0` disables it).
+const timeoutEnv = "CARAPACE_SPEC_TIMEOUT"
+
+// defaultTimeout is the timeout of shell macros if timeoutEnv is unset.
+const defaultTimeout = 5 * time.Second
+
+func shellTimeout(c carapace.Context) (time.Duration, error) {
+	s, ok := c.LookupEnv(timeoutEnv)
+	if !ok || s == "" {
+		return defaultTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("$%v: %w", timeoutEnv, err)
+	}
+	return timeout, nil
+}
+
 func shell(shell, command string) carapace.Action {
 	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
 		args, err := shellArgs(shell, command, c.Args...)
 		if err != nil {
 			return carapace.ActionMessage(err.Error())
 		}
-		return carapace.ActionExecCommand(shell, args...)(func(output []byte) carapace.Action {
+
+		timeout, err := shellTimeout(c)
+		if err != nil {
+			return carapace.ActionMessage(err.Error())
+		}
+		return actionExecCommand(timeout, shell, args...)(func(output []byte) carapace.Action {
 			lines := strings.Split(string(output), "\n")
 			batch := carapace.Batch()
 			for _, line := range lines {
@@ -67,6 +96,54 @@ func shell(shell, command string) carapace.Action {
 	})
 }
 
+// actionExecCommand is like carapace.ActionExecCommand but kills the process group of the command after given timeout (if any).
+// Nothing is executed for an untrusted spec.
+func actionExecCommand(timeout time.Duration, name string, arg ...string) func(f func(output []byte) carapace.Action) carapace.Action {
+	return func(f func(output []byte) carapace.Action) carapace.Action {
+		return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+			if err := checkTrust(c.Getenv(untrustedEnv)); err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+
+			ctx := context.Background()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			var stdout, stderr bytes.Buffer
+			cmd := c.Command(name, arg...)
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			cmd.WaitDelay = time.Second // don't block on output pipes held open by orphaned processes
+			setProcessGroup(cmd)
+
+			if err := runContext(ctx, cmd); err != nil {
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return carapace.ActionMessage("timed out after %v", timeout)
+				}
+				if firstLine := strings.SplitN(strings.TrimSpace(stderr.String()), "\n", 2)[0]; firstLine != "" {
+					return carapace.ActionMessage("%v: %v", name, firstLine)
+				}
+				return carapace.ActionMessage(err.Error())
+			}
+			return f(stdout.Bytes())
+		})
+	}
+}
+
+// runContext runs given command and kills its process group once the context is done.
+func runContext(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, func() { killProcessGroup(cmd) })
+	defer stop()
+	return cmd.Wait()
+}
+
 func shellArgs(shell, command string, arguments ...string) ([]string, error) {
 	if runtime.GOOS == "windows" &&
 		shell != "cmd" &&
@@ -4,6 +4,7 @@ import (
 	_ "embed"
 	"os/exec"
 	"testing"
+	"time"
 
 	"github.com/carapace-sh/carapace"
 	"github.com/carapace-sh/carapace/pkg/sandbox"
@@ -39,3 +40,55 @@ func TestCore(t *testing.T) {
 		}
 	})
 }
+
+func TestCoreStructured(t *testing.T) {
+	sandboxSpec(t, coreSpec)(func(s *sandbox.Sandbox) {
+		for _, format := range []string{"json", "yaml", "csv"} {
+			t.Run(format, func(t *testing.T) {
+				s.Run(format, "").
+					Expect(carapace.ActionValuesDescribed(
+						"one", "first",
+						"two", "second",
+					))
+			})
+		}
+	})
+}
+
+func TestCoreExec(t *testing.T) {
+	sandboxSpec(t, coreSpec)(func(s *sandbox.Sandbox) {
+		s.Run("exec", "").
+			Expect(carapace.ActionValues(
+				"one",
+				"two",
+			))
+
+		s.Run("exec", "one", "").
+			Expect(carapace.ActionValues(
+				"one three",
+				"four",
+			))
+	})
+}
+
+func TestShellTimeout(t *testing.T) {
+	c := carapace.Context{}
+	if timeout, err := shellTimeout(c); err != nil || timeout != defaultTimeout {
+		t.Errorf("expected default timeout, got %v (%v)", timeout, err)
+	}
+
+	c.Setenv(timeoutEnv, "0")
+	if timeout, err := shellTimeout(c); err != nil || timeout != 0 {
+		t.Errorf("expected disabled timeout, got %v (%v)", timeout, err)
+	}
+
+	c.Setenv(timeoutEnv, "10s")
+	if timeout, err := shellTimeout(c); err != nil || timeout != 10*time.Second {
+		t.Errorf("expected configured timeout, got %v (%v)", timeout, err)
+	}
+
+	c.Setenv(timeoutEnv, "invalid")
+	if _, err := shellTimeout(c); err == nil {
+		t.Error("expected error")
+	}
+}
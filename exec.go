@@ -0,0 +1,117 @@
+package spec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	addCoreMacro("exec", MacroI(func(e execArgs) carapace.Action { return e.action() }))
+}
+
+// execArgs executes a command directly without a shell.
+//
+// Either an argv array or a mapping with additional options:
+//
+//	$exec([git, branch, --list, "${C_VALUE}*"])
+//	$exec({args: [git, branch, --list], dir: "${C_FLAG_C}", env: {GIT_PAGER: ""}, output: lines})
+type execArgs struct {
+	Args   []string          `yaml:"args"`
+	Dir    string            `yaml:"dir,omitempty"`    // working directory
+	Env    map[string]string `yaml:"env,omitempty"`    // additional environment variables
+	Output string            `yaml:"output,omitempty"` // output format (tsv, lines, nul)
+}
+
+func (e *execArgs) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode(&e.Args)
+	}
+
+	type plain execArgs // prevent recursion
+	return value.Decode((*plain)(e))
+}
+
+func (e execArgs) action() carapace.Action {
+	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+		if len(e.Args) == 0 {
+			return carapace.ActionMessage("missing args")
+		}
+
+		// substituted per argument as the macro is excluded from substitution in `action.Parse`
+		args := make([]string, 0, len(e.Args))
+		for _, arg := range e.Args {
+			arg, err := c.Envsubst(arg)
+			if err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+			args = append(args, arg)
+		}
+
+		for key, value := range e.Env {
+			value, err := c.Envsubst(value)
+			if err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+			c.Setenv(key, value)
+		}
+
+		if e.Dir != "" {
+			dir, err := c.Envsubst(e.Dir)
+			if err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+			if c.Dir, err = c.Abs(dir); err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+		}
+
+		timeout, err := shellTimeout(c)
+		if err != nil {
+			return carapace.ActionMessage(err.Error())
+		}
+		return actionExecCommand(timeout, args[0], args[1:]...)(func(output []byte) carapace.Action {
+			lines, err := splitOutput(output, e.Output)
+			if err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+
+			batch := carapace.Batch()
+			for _, line := range lines {
+				switch e.Output {
+				case "", "tsv":
+					batch = append(batch, parseValue(line))
+				default:
+					batch = append(batch, carapace.ActionValues(line))
+				}
+			}
+			return batch.ToA()
+		}).Invoke(c).ToA()
+	})
+}
+
+// splitOutput splits given output into non-empty lines.
+//
+//   - `tsv` (default) and `lines` split by newline
+//   - `nul` splits by NUL character
+func splitOutput(output []byte, format string) ([]string, error) {
+	var separator string
+	switch format {
+	case "", "tsv", "lines":
+		separator = "\n"
+	case "nul":
+		separator = "\x00"
+	default:
+		return nil, fmt.Errorf("unknown output format: %#v", format)
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(output), separator) {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
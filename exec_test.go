@@ -0,0 +1,45 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExecArgs(t *testing.T) {
+	var e execArgs
+	if err := yaml.Unmarshal([]byte(`[git, branch, "${C_VALUE}*"]`), &e); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, execArgs{Args: []string{"git", "branch", "${C_VALUE}*"}}, e)
+
+	e = execArgs{}
+	if err := yaml.Unmarshal([]byte(`{args: [git, branch], dir: /tmp, env: {GIT_PAGER: ""}, output: nul}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, execArgs{
+		Args:   []string{"git", "branch"},
+		Dir:    "/tmp",
+		Env:    map[string]string{"GIT_PAGER": ""},
+		Output: "nul",
+	}, e)
+}
+
+func TestSplitOutput(t *testing.T) {
+	lines, err := splitOutput([]byte("one\tfirst\r\n\ntwo\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"one\tfirst", "two"}, lines)
+
+	lines, err = splitOutput([]byte("one\ntwo\x00three\x00"), "nul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"one\ntwo", "three"}, lines)
+
+	if _, err := splitOutput(nil, "unknown"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -1,13 +1,24 @@
 package spec
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"os"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
+// envAnnotation is the flag annotation containing the environment variable of a flag.
+const envAnnotation = "carapace-spec_env"
+
 func addFlagTo(f command.Flag, fset *pflag.FlagSet) error {
 	fs := flagSet{fset}
 	if len(f.Shorthand) > 1 && !fs.IsFork() {
@@ -100,10 +111,36 @@ func addFlagTo(f command.Flag, fset *pflag.FlagSet) error {
 		fs.Lookup(f.Name()).NoOptDefVal = " "
 	}
 
+	if f.Env != "" {
+		BindEnv(fs.Lookup(f.Name()), f.Env)
+		fs.Lookup(f.Name()).Usage += fmt.Sprintf(" [$%v]", f.Env)
+	}
+
+	if f.Type != "" || f.Default != "" || len(f.Enum) > 0 {
+		value, err := newFlagValue(f)
+		if err != nil {
+			return err
+		}
+		flag := fs.Lookup(f.Name())
+		flag.Value = value
+		flag.DefValue = value.String()
+		if f.Optarg && f.Default != "" {
+			flag.NoOptDefVal = f.Default
+		}
+	}
+
 	if f.Hidden {
 		fs.Lookup(f.Name()).Hidden = f.Hidden
 	}
 
+	if f.Deprecated != "" {
+		fs.MarkDeprecated(f.Name(), f.Deprecated)
+	}
+
+	if f.ShorthandDeprecated != "" && f.Shorthand != "" {
+		fs.MarkShorthandDeprecated(f.Name(), f.ShorthandDeprecated)
+	}
+
 	if f.Nargs != 0 {
 		// TODO move to carapace (pflagfork)
 		if field := reflect.ValueOf(fs.Lookup(f.Name())).Elem().FieldByName("Nargs"); field.IsValid() && field.Kind() == reflect.Int {
@@ -113,3 +150,197 @@ func addFlagTo(f command.Flag, fset *pflag.FlagSet) error {
 
 	return nil
 }
+
+// flagEnv returns the value of the environment variable bound to given flag.
+func flagEnv(f *pflag.Flag, lookupEnv func(string) (string, bool)) (string, bool) {
+	if annotation := f.Annotations[envAnnotation]; len(annotation) == 1 {
+		return lookupEnv(annotation[0])
+	}
+	return "", false
+}
+
+// BindEnv binds given flag to an environment variable (see SetFlagsFromEnv).
+func BindEnv(f *pflag.Flag, env string) {
+	if f.Annotations == nil {
+		f.Annotations = make(map[string][]string)
+	}
+	f.Annotations[envAnnotation] = []string{env}
+}
+
+// SetFlagsFromEnv sets flags which were not passed as argument from their environment variable.
+// It is called in `PreRunE` (also by generated code) so that required flags can be set this way.
+func SetFlagsFromEnv(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if value, ok := flagEnv(f, os.LookupEnv); ok && !f.Changed && err == nil {
+			if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("$%v: %w", f.Annotations[envAnnotation][0], setErr)
+			}
+		}
+	})
+	return err
+}
+
+// newFlagValue creates the typed value of a flag.
+func newFlagValue(f command.Flag) (pflag.Value, error) {
+	typ := f.Type
+	switch {
+	case typ != "":
+	case len(f.Enum) > 0:
+		typ = "enum"
+	case f.Value:
+		typ = "string"
+	default:
+		typ = "bool"
+	}
+
+	switch {
+	case typ == "bool" && f.Value:
+		return nil, errors.New("flag of type bool must not take an argument")
+	case typ != "bool" && !f.Value:
+		return nil, fmt.Errorf("flag of type %v must take an argument", typ)
+	case typ != "enum" && len(f.Enum) > 0:
+		return nil, fmt.Errorf("enum values given for flag of type %v", typ)
+	}
+
+	defaults := make([]string, 0)
+	switch {
+	case f.Default == "":
+	case f.Repeatable:
+		var err error
+		if defaults, err = csv.NewReader(strings.NewReader(f.Default)).Read(); err != nil {
+			return nil, fmt.Errorf("invalid default: %w", err)
+		}
+	default:
+		defaults = append(defaults, f.Default)
+	}
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	switch typ {
+	case "bool":
+		if f.Repeatable {
+			if f.Default != "" {
+				return nil, errors.New("default not supported for repeatable flag of type bool")
+			}
+			fs.Count("value", "")
+			break
+		}
+		value, err := parseDefaults(defaults, strconv.ParseBool)
+		if err != nil {
+			return nil, err
+		}
+		fs.Bool("value", first(value), "")
+	case "string":
+		if f.Repeatable {
+			fs.StringSlice("value", defaults, "")
+		} else {
+			fs.String("value", f.Default, "")
+		}
+	case "int":
+		value, err := parseDefaults(defaults, strconv.Atoi)
+		if err != nil {
+			return nil, err
+		}
+		if f.Repeatable {
+			fs.IntSlice("value", value, "")
+		} else {
+			fs.Int("value", first(value), "")
+		}
+	case "float":
+		value, err := parseDefaults(defaults, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+		if err != nil {
+			return nil, err
+		}
+		if f.Repeatable {
+			fs.Float64Slice("value", value, "")
+		} else {
+			fs.Float64("value", first(value), "")
+		}
+	case "duration":
+		value, err := parseDefaults(defaults, time.ParseDuration)
+		if err != nil {
+			return nil, err
+		}
+		if f.Repeatable {
+			fs.DurationSlice("value", value, "")
+		} else {
+			fs.Duration("value", first(value), "")
+		}
+	case "enum":
+		if len(f.Enum) == 0 {
+			return nil, errors.New("missing enum values")
+		}
+		for _, value := range defaults {
+			if !slices.Contains(f.Enum, value) {
+				return nil, fmt.Errorf("invalid default %#v: expected one of %v", value, strings.Join(f.Enum, ", "))
+			}
+		}
+		if f.Repeatable {
+			fs.StringSlice("value", defaults, "")
+		} else {
+			fs.String("value", f.Default, "")
+		}
+		return EnumValue(fs.Lookup("value").Value, f.Enum...), nil
+	default:
+		return nil, fmt.Errorf("unknown flag type: %v", typ)
+	}
+	return fs.Lookup("value").Value, nil
+}
+
+func parseDefaults[T any](defaults []string, parse func(s string) (T, error)) ([]T, error) {
+	values := make([]T, 0, len(defaults))
+	for _, s := range defaults {
+		value, err := parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default %#v: %w", s, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func first[T any](values []T) T {
+	var value T
+	if len(values) > 0 {
+		value = values[0]
+	}
+	return value
+}
+
+// enumValue restricts a flag value to given values.
+type enumValue struct {
+	pflag.Value
+	enum       []string
+	repeatable bool
+}
+
+// EnumValue restricts given flag value to given values (used by generated code).
+func EnumValue(value pflag.Value, values ...string) pflag.Value {
+	_, repeatable := value.(pflag.SliceValue)
+	return enumValue{value, values, repeatable}
+}
+
+// GetSlice returns the values of a repeatable flag (the single value otherwise).
+func (e enumValue) GetSlice() []string {
+	if sliceValue, ok := e.Value.(pflag.SliceValue); ok {
+		return sliceValue.GetSlice()
+	}
+	return []string{e.Value.String()}
+}
+
+func (e enumValue) Set(s string) error {
+	values := []string{s}
+	if e.repeatable {
+		var err error
+		if values, err = csv.NewReader(strings.NewReader(s)).Read(); err != nil {
+			return err
+		}
+	}
+
+	for _, value := range values {
+		if !slices.Contains(e.enum, value) {
+			return fmt.Errorf("expected one of %v", strings.Join(e.enum, ", "))
+		}
+	}
+	return e.Value.Set(s)
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
 	"github.com/carapace-sh/carapace/pkg/sandbox"
 )
 
@@ -37,3 +39,83 @@ func TestFlag(t *testing.T) {
 				Tag("longhand flags"))
 	})
 }
+
+func TestFlagType(t *testing.T) {
+	cmd, err := Command{
+		Name: "typed",
+		Flags: command.FlagSet{
+			"count":   {Longhand: "count", Value: true, Type: "int", Default: "3"},
+			"ratio":   {Longhand: "ratio", Value: true, Repeatable: true, Type: "float", Default: "0.5,1"},
+			"timeout": {Longhand: "timeout", Value: true, Type: "duration", Default: "1m"},
+			"color":   {Longhand: "color", Value: true, Enum: []string{"auto", "always", "never"}},
+			"verbose": {Longhand: "verbose", Type: "bool", Default: "true"},
+		},
+	}.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string][2]string{
+		"count":   {"int", "3"},
+		"ratio":   {"float64Slice", "[0.500000,1.000000]"},
+		"timeout": {"duration", "1m0s"},
+		"color":   {"string", ""},
+		"verbose": {"bool", "true"},
+	} {
+		f := cmd.Flag(name)
+		assert.Equal(t, expected, [2]string{f.Value.Type(), f.DefValue})
+	}
+
+	assert.Equal(t, `invalid argument "sometimes" for "--color" flag: expected one of auto, always, never`, cmd.ParseFlags([]string{"--color", "sometimes"}).Error())
+	assert.Equal(t, `invalid argument "many" for "--count" flag: strconv.ParseInt: parsing "many": invalid syntax`, cmd.ParseFlags([]string{"--count", "many"}).Error())
+
+	test := func(f command.Flag, expected string) {
+		t.Run(expected, func(t *testing.T) {
+			_, err := Command{Name: "invalid", Flags: command.FlagSet{f.Name(): f}}.ToCobraE()
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			assert.Equal(t, expected, err.Error())
+		})
+	}
+	test(command.Flag{Longhand: "count", Type: "int"}, `flags["--count"]: flag of type int must take an argument`)
+	test(command.Flag{Longhand: "count", Value: true, Type: "number"}, `flags["--count="]: unknown flag type: number`)
+	test(command.Flag{Longhand: "count", Value: true, Type: "int", Default: "x"}, `flags["--count="]: invalid default "x": strconv.Atoi: parsing "x": invalid syntax`)
+	test(command.Flag{Longhand: "color", Value: true, Type: "enum"}, `flags["--color="]: missing enum values`)
+	test(command.Flag{Longhand: "color", Value: true, Enum: []string{"auto"}, Default: "never"}, `flags["--color="]: invalid default "never": expected one of auto`)
+}
+
+func TestFlagEnv(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "fromenv")
+	t.Setenv("TEST_COUNT", "many")
+
+	cmd, err := Command{
+		Name: "env",
+		Flags: command.FlagSet{
+			"token": {Longhand: "token", Value: true, Description: "token to use", Env: "TEST_TOKEN"},
+			"count": {Longhand: "count", Value: true, Type: "int", Env: "TEST_COUNT"},
+		},
+	}.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "token to use [$TEST_TOKEN]", cmd.Flag("token").Usage)
+
+	if err := cmd.ParseFlags([]string{"--count", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFlagsFromEnv(cmd); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "fromenv", cmd.Flag("token").Value.String())
+	assert.Equal(t, "1", cmd.Flag("count").Value.String())
+
+	t.Setenv("TEST_TOKEN", "")
+	cmd, _ = Command{
+		Name: "env",
+		Flags: command.FlagSet{
+			"count": {Longhand: "count", Value: true, Type: "int", Env: "TEST_COUNT"},
+		},
+	}.ToCobraE()
+	assert.Equal(t, `$TEST_COUNT: invalid argument "many" for "--count" flag: strconv.ParseInt: parsing "many": invalid syntax`, SetFlagsFromEnv(cmd).Error())
+}
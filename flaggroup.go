@@ -0,0 +1,186 @@
+package spec
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/internal/pflagfork"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// flag group annotations as set by cobra
+const (
+	exclusiveAnnotation        = "cobra_annotation_mutually_exclusive"
+	requiredTogetherAnnotation = "cobra_annotation_required_if_others_set"
+	oneRequiredAnnotation      = "cobra_annotation_one_required"
+)
+
+// requiresAnnotation is the flag annotation containing the flags required by a flag.
+const requiresAnnotation = "carapace-spec_requires"
+
+func (c Command) markFlagGroups(cmd *cobra.Command) error {
+	flags := cmd.LocalFlags() // merges persistent flags of the command
+	for _, section := range []struct {
+		name   string
+		groups [][]string
+		mark   func(...string)
+	}{
+		{"exclusiveflags", c.ExclusiveFlags, cmd.MarkFlagsMutuallyExclusive},
+		{"requiredtogether", c.RequiredTogether, cmd.MarkFlagsRequiredTogether},
+		{"onerequired", c.OneRequired, cmd.MarkFlagsOneRequired},
+	} {
+		for index, group := range section.groups {
+			for i, name := range group {
+				if flags.Lookup(name) == nil {
+					return command.WrapError(fmt.Errorf("unknown flag: %#v", name), command.JoinPath(section.name, index, i))
+				}
+			}
+			section.mark(group...)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Requires)) {
+		if flags.Lookup(name) == nil {
+			return command.WrapError(fmt.Errorf("unknown flag: %#v", name), command.JoinPath("requires", command.Key(name)))
+		}
+		for index, required := range c.Requires[name] {
+			if flags.Lookup(required) == nil {
+				return command.WrapError(fmt.Errorf("unknown flag: %#v", required), command.JoinPath("requires", command.Key(name), index))
+			}
+		}
+		flags.SetAnnotation(name, requiresAnnotation, c.Requires[name])
+	}
+
+	if len(c.ExclusiveFlags) > 0 || len(c.RequiredTogether) > 0 || len(c.OneRequired) > 0 || len(c.Requires) > 0 {
+		carapace.Gen(cmd).PreRun(func(cmd *cobra.Command, args []string) {
+			constrainFlags(cmd, args)
+		})
+	}
+	return nil
+}
+
+// validateRequires checks that flags required by set flags are set as well.
+func validateRequires(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if !f.Changed || err != nil {
+			return
+		}
+		for _, name := range f.Annotations[requiresAnnotation] {
+			if !cmd.Flags().Changed(name) {
+				err = fmt.Errorf("flag --%v requires --%v", f.Name, name)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// constrainFlags adjusts flags for completion based on their groups and the flags already set in given args.
+//
+//   - flags conflicting with set flags are hidden
+//   - flags missing for set flags are marked as required
+func constrainFlags(cmd *cobra.Command, args []string) {
+	set := setFlags(cmd, args)
+	isSet := func(name string) bool { return set[name] }
+
+	require := func(names ...string) {
+		for _, name := range names {
+			if !set[name] {
+				cmd.Flags().SetAnnotation(name, cobra.BashCompOneRequiredFlag, []string{"true"})
+			}
+		}
+	}
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		for _, group := range f.Annotations[exclusiveAnnotation] {
+			if names := strings.Split(group, " "); !set[f.Name] && slices.ContainsFunc(names, isSet) {
+				f.Hidden = true
+			}
+		}
+		for _, group := range f.Annotations[requiredTogetherAnnotation] {
+			if names := strings.Split(group, " "); slices.ContainsFunc(names, isSet) {
+				require(names...)
+			}
+		}
+		for _, group := range f.Annotations[oneRequiredAnnotation] {
+			if names := strings.Split(group, " "); !slices.ContainsFunc(names, isSet) {
+				require(names...)
+			}
+		}
+		if set[f.Name] {
+			require(f.Annotations[requiresAnnotation]...)
+		}
+	})
+}
+
+// setFlags returns the names of flags contained in given args.
+func setFlags(cmd *cobra.Command, args []string) map[string]bool {
+	set := make(map[string]bool)
+	skip := false
+	mark := func(f *pflag.Flag, hasValue bool) {
+		set[f.Name] = true
+		if flag := (pflagfork.Flag{Flag: f}); flag.TakesValue() && !flag.IsOptarg() && !hasValue {
+			skip = true // next arg is the value
+		}
+	}
+
+	for _, arg := range args {
+		switch {
+		case skip:
+			skip = false
+			continue
+		case arg == "--":
+			return set
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			continue
+		}
+
+		matched := false
+		cmd.Flags().VisitAll(func(f *pflag.Flag) {
+			if flag := (pflagfork.Flag{Flag: f}); !matched && flag.Matches(arg, false) {
+				matched = true
+				mark(f, strings.ContainsRune(arg, flag.OptargDelimiter()))
+			}
+		})
+
+		if !matched && !strings.HasPrefix(arg, "--") { // shorthand series
+			series := strings.TrimPrefix(arg, "-")
+			for index, r := range series {
+				f := cmd.Flags().ShorthandLookup(string(r))
+				if f == nil {
+					break
+				}
+				mark(f, index < len(series)-1)
+				if (pflagfork.Flag{Flag: f}).TakesValue() {
+					break // remainder is the value
+				}
+			}
+		}
+	}
+	return set
+}
+
+// flagGroups returns the flag groups of given annotation.
+func flagGroups(cmd *cobra.Command, annotation string) [][]string {
+	groups := make([]string, 0)
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		for _, group := range f.Annotations[annotation] {
+			if !slices.Contains(groups, group) {
+				groups = append(groups, group)
+			}
+		}
+	})
+	slices.Sort(groups)
+
+	result := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, strings.Split(group, " "))
+	}
+	return result
+}
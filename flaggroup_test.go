@@ -0,0 +1,127 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"github.com/spf13/cobra"
+)
+
+func flagGroupCmd(t *testing.T) *cobra.Command {
+	cmd, err := Command{
+		Name: "flaggroup",
+		Flags: command.FlagSet{
+			"add":      {Longhand: "add", Shorthand: "a"},
+			"delete":   {Longhand: "delete", Shorthand: "d"},
+			"user":     {Longhand: "user", Shorthand: "u", Value: true},
+			"password": {Longhand: "password", Value: true},
+			"cert":     {Longhand: "cert", Value: true},
+			"key":      {Longhand: "key", Value: true},
+		},
+		ExclusiveFlags:   [][]string{{"add", "delete"}},
+		RequiredTogether: [][]string{{"user", "password"}},
+		OneRequired:      [][]string{{"add", "delete"}},
+		Requires:         map[string][]string{"key": {"cert"}},
+	}.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestSetFlags(t *testing.T) {
+	cmd := flagGroupCmd(t)
+	assert.Equal(t, map[string]bool{"add": true, "user": true, "key": true}, setFlags(cmd, []string{"-au", "--cert", "--key=x", "pos"}))
+	assert.Equal(t, map[string]bool{"user": true}, setFlags(cmd, []string{"-u", "-a", "--", "--key"}))
+}
+
+func TestConstrainFlags(t *testing.T) {
+	required := func(cmd *cobra.Command) []string {
+		names := make([]string, 0)
+		for _, name := range []string{"add", "delete", "user", "password", "cert", "key"} {
+			if annotation := cmd.Flag(name).Annotations[cobra.BashCompOneRequiredFlag]; len(annotation) == 1 {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	cmd := flagGroupCmd(t)
+	constrainFlags(cmd, []string{})
+	assert.Equal(t, []string{"add", "delete"}, required(cmd))
+
+	cmd = flagGroupCmd(t)
+	constrainFlags(cmd, []string{"--add", "--user", "u", "--key", "k"})
+	assert.Equal(t, []string{"password", "cert"}, required(cmd))
+	assert.Equal(t, true, cmd.Flag("delete").Hidden)
+	assert.Equal(t, false, cmd.Flag("add").Hidden)
+}
+
+func TestValidateRequires(t *testing.T) {
+	cmd := flagGroupCmd(t)
+	if err := cmd.ParseFlags([]string{"--key", "k"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "flag --key requires --cert", validateRequires(cmd).Error())
+
+	if err := cmd.ParseFlags([]string{"--cert", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateRequires(cmd); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMarkFlagGroupsUnknown(t *testing.T) {
+	_, err := Command{
+		Name:     "unknown",
+		Flags:    command.FlagSet{"key": {Longhand: "key", Value: true}},
+		Requires: map[string][]string{"key": {"cert"}},
+	}.ToCobraE()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	assert.Equal(t, `requires["key"][0]: unknown flag: "cert"`, err.Error())
+}
+
+func TestMarkFlagGroupsPersistent(t *testing.T) {
+	c := Command{
+		Name: "persistent",
+		PersistentFlags: command.FlagSet{
+			"add":    {Longhand: "add", Persistent: true},
+			"delete": {Longhand: "delete", Persistent: true},
+			"cert":   {Longhand: "cert", Value: true, Persistent: true},
+		},
+		Flags:            command.FlagSet{"key": {Longhand: "key", Value: true}},
+		ExclusiveFlags:   [][]string{{"add", "delete"}},
+		RequiredTogether: [][]string{{"key", "cert"}},
+		OneRequired:      [][]string{{"add", "delete"}},
+		Requires:         map[string][]string{"cert": {"key"}, "key": {"cert"}},
+	}
+	if err := command.Command(c).Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, err := c.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]string{{"add", "delete"}}, flagGroups(cmd, exclusiveAnnotation))
+	assert.Equal(t, [][]string{{"key", "cert"}}, flagGroups(cmd, requiredTogetherAnnotation))
+	assert.Equal(t, [][]string{{"add", "delete"}}, flagGroups(cmd, oneRequiredAnnotation))
+	assert.Equal(t, []string{"key"}, cmd.PersistentFlags().Lookup("cert").Annotations[requiresAnnotation])
+}
+
+func TestValidateInheritedFlagGroups(t *testing.T) {
+	err := command.Command{
+		Name:            "root",
+		PersistentFlags: command.FlagSet{"verbose": {Longhand: "verbose", Persistent: true}},
+		Commands: []command.Command{{
+			Name:           "sub",
+			Flags:          command.FlagSet{"quiet": {Longhand: "quiet"}},
+			ExclusiveFlags: [][]string{{"quiet", "verbose"}},
+		}},
+	}.Validate()
+	assert.Equal(t, `commands[0].exclusiveflags[0][1]: unknown flag: "verbose"`, err.Error())
+}
@@ -51,3 +51,10 @@ func (f *flagSet) StringN(name, shorthand, value, usage string) {
 func (f *flagSet) StringS(name, shorthand, value, usage string) {
 	f.call("StringS", reflect.ValueOf(name), reflect.ValueOf(shorthand), reflect.ValueOf(value), reflect.ValueOf(usage))
 }
+
+func (f flagSet) IsInterspersed() bool {
+	if field := reflect.ValueOf(f.FlagSet).Elem().FieldByName("interspersed"); field.IsValid() && field.Kind() == reflect.Bool {
+		return field.Bool()
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+)
+
+// CompletionTest is a completion test of a spec (as listed in a sidecar `*.test.yaml` file).
+type CompletionTest struct {
+	Name    string           `yaml:"name,omitempty"`
+	Args    []string         `yaml:"args,flow"` // arguments preceding the current word
+	Current string           `yaml:"current"`   // current word
+	Expect  CompletionResult `yaml:"expect"`
+}
+
+// CompletionResult is the result of a completion (as exported by carapace).
+type CompletionResult struct {
+	Values   []CompletionResultValue `yaml:"values,omitempty" json:"values"`
+	Messages []string                `yaml:"messages,omitempty" json:"messages"`
+	Usage    string                  `yaml:"usage,omitempty" json:"usage"`
+}
+
+type CompletionResultValue struct {
+	Value       string `yaml:"value" json:"value"`
+	Description string `yaml:"description,omitempty" json:"description"`
+	Style       string `yaml:"style,omitempty" json:"style"`
+	Tag         string `yaml:"tag,omitempty" json:"tag"`
+}
+
+// TestPath returns the path of the sidecar test file for given spec (`example.yaml` -> `example.test.yaml`).
+func TestPath(path string) string {
+	return strings.TrimSuffix(path, ".yaml") + ".test.yaml"
+}
+
+// Complete invokes the completion in-process for given args and current word.
+//
+// The output of the hidden `_carapace export` command is captured by the command itself
+// (carapace's sandbox is bound to `testing.T` and thus can't be used outside of tests).
+// Specs loaded from a file are verified against the trust store (see VerifyTrust).
+func (c Command) Complete(args []string, current string) (*CompletionResult, error) {
+	cmd, err := c.ToCobraE()
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyTrust((*command.Command)(&c), cmd); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs(append(append([]string{"_carapace", "export", ""}, args...), current))
+	if err := cmd.Execute(); err != nil {
+		return nil, err
+	}
+	if stderr.Len() > 0 {
+		return nil, errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return parseExport(stdout.Bytes())
+}
+
+// parseExport parses the output of carapace's `export` format.
+func parseExport(output []byte) (*CompletionResult, error) {
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil, errors.New("missing completion output")
+	}
+
+	var completion CompletionResult
+	if err := json.Unmarshal(output, &completion); err != nil {
+		return nil, err
+	}
+	return &completion, nil
+}
@@ -0,0 +1,109 @@
+package spec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseExport(t *testing.T) {
+	completion, err := parseExport([]byte(`{"version":"v1.13.0","messages":["some error"],"nospace":"","usage":"some usage","values":[{"value":"one","display":"one","description":"first","tag":"numbers"},{"value":"two","display":"two","style":"blue"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, CompletionResult{
+		Values: []CompletionResultValue{
+			{Value: "one", Description: "first", Tag: "numbers"},
+			{Value: "two", Style: "blue"},
+		},
+		Messages: []string{"some error"},
+		Usage:    "some usage",
+	}, *completion)
+
+	if _, err := parseExport([]byte("\n")); err == nil {
+		t.Error("expected error for missing output")
+	}
+}
+
+func TestCompletionTest(t *testing.T) {
+	var testCases []CompletionTest
+	if err := yaml.Unmarshal([]byte(`
+- name: flag
+  args: [--color]
+  current: a
+  expect:
+    values:
+      - value: always
+        description: always colorize
+`), &testCases); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []CompletionTest{{
+		Name:    "flag",
+		Args:    []string{"--color"},
+		Current: "a",
+		Expect: CompletionResult{
+			Values: []CompletionResultValue{{Value: "always", Description: "always colorize"}},
+		},
+	}}, testCases)
+	assert.Equal(t, "example/pkill.test.yaml", TestPath("example/pkill.yaml"))
+}
+
+func TestComplete(t *testing.T) {
+	var c Command
+	if err := yaml.Unmarshal([]byte(`name: complete
+flags:
+  --color=: colorize
+completion:
+  flag:
+    color: ["always\talways colorize", never]
+  positional:
+    - [one, two]
+`), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	completion, err := c.Complete([]string{"--color"}, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []CompletionResultValue{{Value: "always", Description: "always colorize"}}, completion.Values)
+
+	completion, err = c.Complete([]string{}, "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []CompletionResultValue{{Value: "two"}}, completion.Values)
+}
+
+func TestCompleteUntrusted(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // linux
+	t.Setenv("HOME", dir)            // darwin
+	t.Setenv("AppData", dir)         // windows
+
+	path := filepath.Join(dir, "untrusted.yaml")
+	marker := filepath.Join(dir, "executed")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("name: untrusted\ncompletion:\n  positional:\n    - [\"$(touch %v)\"]\n", marker)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := command.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	completion, err := Command(*cmd).Complete([]string{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{fmt.Sprintf("untrusted spec: run `carapace-spec trust %v` to allow command execution", path)}, completion.Messages)
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("expected shell macro not to be executed")
+	}
+}
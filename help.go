@@ -0,0 +1,104 @@
+package spec
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const argumentsAnnotation = "carapace-spec_arguments"
+
+// addDocumentation maps documentation and examples to the help output.
+func (c Command) addDocumentation(cmd *cobra.Command) error {
+	cmd.Long = strings.TrimSpace(c.Documentation.Command)
+	cmd.Example = formatExamples(c.Examples)
+
+	if arguments := formatArguments(c); arguments != "" {
+		if cmd.Annotations == nil {
+			cmd.Annotations = make(map[string]string)
+		}
+		cmd.Annotations[argumentsAnnotation] = arguments
+		cmd.SetUsageTemplate(usageTemplate(cmd.UsageTemplate()))
+	}
+
+	if len(c.Documentation.Flag) > 0 {
+		owner := cmd
+		help := cmd.HelpFunc()
+		cmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+			if cmd != owner { // inherited by a subcommand
+				help(cmd, args)
+				return
+			}
+
+			// flag documentation is only added temporarily as the usage is also used for completion
+			for name, documentation := range c.Documentation.Flag {
+				if f := cmd.Flag(name); f != nil {
+					usage := f.Usage
+					f.Usage = strings.TrimSpace(usage + "\n" + strings.TrimSpace(documentation))
+					defer func() { f.Usage = usage }()
+				}
+			}
+			help(cmd, args)
+		})
+	}
+	return nil
+}
+
+// formatExamples formats examples as used by cobra (`# description` followed by the command).
+func formatExamples(examples map[string]string) string {
+	formatted := make([]string, 0, len(examples))
+	for _, example := range slices.Sorted(maps.Keys(examples)) {
+		if description := examples[example]; description != "" {
+			formatted = append(formatted, fmt.Sprintf("  # %v\n  %v", description, example))
+		} else {
+			formatted = append(formatted, "  "+example)
+		}
+	}
+	return strings.Join(formatted, "\n\n")
+}
+
+// formatArguments formats the documentation of positional and dash arguments.
+func formatArguments(c Command) string {
+	type argument struct{ name, description string }
+	arguments := make([]argument, 0)
+	for index, s := range c.Documentation.Positional {
+		arguments = append(arguments, argument{fmt.Sprintf("%v", index+1), s})
+	}
+	if s := c.Documentation.PositionalAny; s != "" {
+		arguments = append(arguments, argument{"...", s})
+	}
+	for index, s := range c.Documentation.Dash {
+		arguments = append(arguments, argument{fmt.Sprintf("-- %v", index+1), s})
+	}
+	if s := c.Documentation.DashAny; s != "" {
+		arguments = append(arguments, argument{"-- ...", s})
+	}
+
+	width := 0
+	for _, a := range arguments {
+		width = max(width, len(a.name))
+	}
+
+	lines := make([]string, 0, len(arguments))
+	for _, a := range arguments {
+		description := strings.ReplaceAll(strings.TrimSpace(a.description), "\n", "\n"+strings.Repeat(" ", width+5))
+		lines = append(lines, fmt.Sprintf("  %-*v   %v", width, a.name, description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// usageTemplate adds an arguments section to given usage template.
+func usageTemplate(template string) string {
+	section := fmt.Sprintf(`{{with index .Annotations %#v}}
+
+Arguments:
+{{.}}{{end}}`, argumentsAnnotation)
+
+	if index := strings.Index(template, "{{if gt (len .Aliases) 0}}"); index >= 0 {
+		return template[:index] + section + template[index:]
+	}
+	return strings.TrimSuffix(template, "\n") + section + "\n"
+}
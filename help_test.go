@@ -0,0 +1,53 @@
+package spec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
+)
+
+func TestHelp(t *testing.T) {
+	cmd, err := Command{
+		Name:        "help",
+		Description: "help example",
+		Flags: command.FlagSet{
+			"file": {Longhand: "file", Shorthand: "f", Value: true, Description: "file to read"},
+		},
+		Documentation: command.Documentation{
+			Command:       "Help shows documentation.\n",
+			Flag:          map[string]string{"file": "Reads stdin when omitted."},
+			Positional:    []string{"first argument"},
+			PositionalAny: "remaining arguments",
+		},
+		Examples: map[string]string{
+			"help -f file": "read file",
+			"help one":     "",
+		},
+		Commands: []command.Command{{Name: "sub"}},
+	}.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Help shows documentation.", cmd.Long)
+	assert.Equal(t, "  # read file\n  help -f file\n\n  help one", cmd.Example)
+
+	usage := cmd.UsageString()
+	if !strings.Contains(usage, "\n\nArguments:\n  1     first argument\n  ...   remaining arguments\n\n") {
+		t.Errorf("missing arguments in usage:\n%v", usage)
+	}
+	if strings.Contains(cmd.Commands()[0].UsageString(), "Arguments:") {
+		t.Error("arguments must not be inherited by subcommands")
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.HelpFunc()(cmd, nil)
+	if !strings.Contains(out.String(), "file to read\n") || !strings.Contains(out.String(), "Reads stdin when omitted.") {
+		t.Errorf("missing flag documentation in help:\n%v", out.String())
+	}
+	assert.Equal(t, "file to read", cmd.Flag("file").Usage)
+}
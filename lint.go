@@ -0,0 +1,163 @@
+package spec
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+)
+
+// Diagnostic is a problem found in a spec.
+type Diagnostic struct {
+	Position command.Position `json:"position"`
+	Path     string           `json:"path"`
+	Message  string           `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return (&command.Error{Position: d.Position, Path: d.Path, Err: errors.New(d.Message)}).Error()
+}
+
+// Lint checks the spec for problems which would otherwise only surface during completion.
+// Diagnostics are ordered by their position.
+func (c Command) Lint() []Diagnostic {
+	l := &linter{diagnostics: make([]Diagnostic, 0)}
+	var validationErrs command.ValidationErrors
+	if errors.As(command.Command(c).Validate(), &validationErrs) {
+		for _, e := range validationErrs {
+			l.report(e.Path, "%v", e.Err)
+		}
+	}
+	l.command("", command.Command(c))
+	for index, diagnostic := range l.diagnostics {
+		l.diagnostics[index].Position = command.Command(c).Position(diagnostic.Path)
+	}
+	slices.SortStableFunc(l.diagnostics, func(a, b Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(a.Position.File, b.Position.File),
+			cmp.Compare(a.Position.Line, b.Position.Line),
+			cmp.Compare(a.Position.Column, b.Position.Column),
+		)
+	})
+	return l.diagnostics
+}
+
+type linter struct {
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(path, format string, a ...any) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Path:    path,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// command checks macros and flag types (structural problems are covered by `command.Validate`).
+func (l *linter) command(path string, c command.Command) {
+	for _, field := range []struct {
+		name    string
+		flagSet command.FlagSet
+	}{{"flags", c.Flags}, {"persistentflags", c.PersistentFlags}} {
+		for _, name := range slices.Sorted(maps.Keys(field.flagSet)) {
+			f := field.flagSet[name]
+			if _, err := newFlagValue(f); err != nil {
+				l.report(command.JoinPath(path, field.name, command.Key(f.Definition())), "%v", err)
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Completion.Flag)) {
+		l.completion(command.JoinPath(path, "completion", "flag", command.Key(name)), c.Completion.Flag[name])
+	}
+	for index, a := range c.Completion.Positional {
+		l.completion(command.JoinPath(path, "completion", "positional", index), a)
+	}
+	l.action(command.JoinPath(path, "completion", "positionalany"), c.Completion.PositionalAny)
+	for index, values := range c.Completion.Dash {
+		l.action(command.JoinPath(path, "completion", "dash", index), values)
+	}
+	l.action(command.JoinPath(path, "completion", "dashany"), c.Completion.DashAny)
+
+	if c.Run.Type() == "macro" {
+		l.run(command.JoinPath(path, "run"), c.Run)
+	}
+
+	for index, subcommand := range c.Commands {
+		l.command(command.JoinPath(path, "commands", index), subcommand)
+	}
+}
+
+func (l *linter) completion(path string, a command.Action) {
+	if !a.IsConditional() {
+		l.action(path, a.Values)
+		return
+	}
+
+	for index, condition := range a.When {
+		l.action(command.JoinPath(path, "when", index, "then"), condition.Then)
+	}
+	l.action(command.JoinPath(path, "else"), a.Else)
+}
+
+func (l *linter) action(path string, values []string) {
+	for index, value := range values {
+		splitted := strings.Split(value, " ||| ")
+		if strings.HasPrefix(splitted[0], "$") && !strings.HasPrefix(splitted[0], "${") {
+			l.macro(command.JoinPath(path, index), splitted[0])
+		}
+		for _, s := range splitted[1:] {
+			if !isModifier(s) {
+				l.report(command.JoinPath(path, index), "unknown modifier: %#v", s)
+			}
+		}
+	}
+}
+
+func (l *linter) macro(path, s string) {
+	r := regexp.MustCompile(`^\$(?P<macro>[^(]*)(\((?P<arg>.*)\))?$`)
+	matches := r.FindStringSubmatch(s)
+	if matches == nil {
+		l.report(path, "malformed macro: %#v", s)
+		return
+	}
+
+	if isModifier(s) {
+		return // generic modifier
+	}
+
+	if strings.HasPrefix(matches[1], "_") && !strings.HasPrefix(matches[1], "_.") {
+		l.report(path, `"$_" deprecated: replace %#v with %#v`, "$"+matches[1], "$carapace."+strings.TrimPrefix(matches[1], "_"))
+		return
+	}
+
+	prefix := fmt.Sprintf("$%v.", executable())
+	switch {
+	case !strings.HasPrefix(matches[1], "_.") && strings.Contains(matches[1], ".") && !strings.HasPrefix(s, prefix):
+		return // macro provided by an external command
+	default:
+		if after, ok := strings.CutPrefix(s, prefix); ok {
+			s = "$_." + after
+		}
+		if _, err := macros.Lookup(s); err != nil {
+			l.report(path, "%v", err)
+		}
+	}
+}
+
+func (l *linter) run(path string, r command.Run) {
+	splitted := strings.Split(string(r), " ||| ")
+	if _, err := runMacros(nil).Lookup(splitted[0]); err != nil {
+		l.report(path, "%v", err)
+	}
+	for _, s := range splitted[1:] {
+		if !strings.HasPrefix(s, "$chdir(") {
+			l.report(path, "invalid modifier: %#v", s)
+		}
+	}
+}
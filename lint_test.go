@@ -0,0 +1,44 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLint(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte(`
+name: lint
+persistentflags:
+  --persistent=: persistent flag
+commands:
+  - name: sub
+    aliases: [s]
+    flags:
+      -l, --local=: local flag
+    exclusiveflags:
+      - [local, unknown]
+    completion:
+      flag:
+        local: ["$files", "$filess", "$list(,)"]
+        persistent: ["one ||| $prefix(x) ||| $unknown"]
+        missing: [one, two]
+  - name: s
+  - name: run
+    run: "$unknown(echo)"
+`), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []Diagnostic{
+		{Position: command.Position{Line: 11, Column: 17}, Path: `commands[0].exclusiveflags[0][1]`, Message: `unknown flag: "unknown"`},
+		{Position: command.Position{Line: 14, Column: 27}, Path: `commands[0].completion.flag["local"][1]`, Message: `unknown macro: "$filess"`},
+		{Position: command.Position{Line: 15, Column: 22}, Path: `commands[0].completion.flag["persistent"][0]`, Message: `unknown modifier: "$unknown"`},
+		{Position: command.Position{Line: 16, Column: 9}, Path: `commands[0].completion.flag["missing"]`, Message: `unknown flag: "missing"`},
+		{Position: command.Position{Line: 17, Column: 5}, Path: `commands[1].name`, Message: `duplicate name "s" (already used by commands[0])`},
+		{Position: command.Position{Line: 19, Column: 5}, Path: `commands[2].run`, Message: `unknown macro: "$unknown(echo)"`},
+	}, cmd.Lint())
+}
@@ -3,13 +3,16 @@ package spec
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace/pkg/cache/key"
 	"github.com/carapace-sh/carapace/pkg/traverse"
 )
 
 type modifier struct {
 	carapace.Action
+	id string // identifies the modified action (used as cache key)
 }
 
 func (m modifier) Parse(s string) carapace.Action {
@@ -21,34 +24,62 @@ func (m modifier) Parse(s string) carapace.Action {
 			return carapace.ActionMessage(err.Error())
 		}
 
-		modifiers := map[string]Macro{
-			"$chdir":      MacroI(m.chdir),
-			"$filter":     MacroV(m.Action.Filter),
-			"$filterargs": MacroN(m.Action.FilterArgs),
-			"$list":       MacroI(m.Action.List),
-			"$multiparts": MacroV(m.Action.MultiParts),
-			"$nospace":    MacroI(func(s string) carapace.Action { return m.Action.NoSpace([]rune(s)...) }),
-			"$noprefix":   MacroI(func(s string) carapace.Action { return m.Action.NoPrefix([]rune(s)...) }),
-			"$prefix":     MacroI(m.Action.Prefix),
-			"$retain":     MacroV(m.Action.Retain),
-			"$shift":      MacroI(m.Action.Shift),
-			"$split":      MacroN(m.Action.Split),
-			"$splitp":     MacroN(m.Action.SplitP),
-			"$suffix":     MacroI(m.Action.Suffix),
-			"$suppress":   MacroI(func(s string) carapace.Action { return m.Action.Suppress(s) }),
-			"$style":      MacroI(m.Action.Style),
-			"$tag":        MacroI(m.Action.Tag),
-			"$uniquelist": MacroI(m.Action.UniqueList),
-			"$usage":      MacroI(func(s string) carapace.Action { return m.Action.Usage(s) }),
-		}
-
-		if modifier, ok := modifiers[strings.SplitN(s, "(", 2)[0]]; ok {
+		if modifier, ok := m.modifiers()[strings.SplitN(s, "(", 2)[0]]; ok {
 			return modifier.Parse(s)
 		}
 		return carapace.ActionMessage("unknown macro: %#v", s)
 	})
 }
 
+func (m modifier) modifiers() map[string]Macro {
+	return map[string]Macro{
+		"$cache":      MacroI(m.cache),
+		"$chdir":      MacroI(m.chdir),
+		"$filter":     MacroV(m.Action.Filter),
+		"$filterargs": MacroN(m.Action.FilterArgs),
+		"$list":       MacroI(m.Action.List),
+		"$multiparts": MacroV(m.Action.MultiParts),
+		"$nospace":    MacroI(func(s string) carapace.Action { return m.Action.NoSpace([]rune(s)...) }),
+		"$noprefix":   MacroI(func(s string) carapace.Action { return m.Action.NoPrefix([]rune(s)...) }),
+		"$prefix":     MacroI(m.Action.Prefix),
+		"$retain":     MacroV(m.Action.Retain),
+		"$shift":      MacroI(m.Action.Shift),
+		"$split":      MacroN(m.Action.Split),
+		"$splitp":     MacroN(m.Action.SplitP),
+		"$suffix":     MacroI(m.Action.Suffix),
+		"$suppress":   MacroI(func(s string) carapace.Action { return m.Action.Suppress(s) }),
+		"$style":      MacroI(m.Action.Style),
+		"$tag":        MacroI(m.Action.Tag),
+		"$timeout":    MacroI(m.timeout),
+		"$uniquelist": MacroI(m.Action.UniqueList),
+		"$usage":      MacroI(func(s string) carapace.Action { return m.Action.Usage(s) }),
+	}
+}
+
+// isModifier returns whether given macro (e.g. `$cache(1m)`) is a generic modifier.
+func isModifier(s string) bool {
+	_, ok := (modifier{}).modifiers()[strings.SplitN(s, "(", 2)[0]]
+	return ok
+}
+
+// cache caches the action for given duration (`<duration>[,<key>...]`).
+// The cache is keyed by the action, the working directory and any additional keys.
+func (m modifier) cache(s string) carapace.Action {
+	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+		splitted := strings.Split(s, ",")
+		timeout, err := time.ParseDuration(strings.TrimSpace(splitted[0]))
+		if err != nil {
+			return carapace.ActionMessage(err.Error())
+		}
+
+		keys := []string{m.id, c.Dir}
+		for _, k := range splitted[1:] {
+			keys = append(keys, strings.TrimSpace(k))
+		}
+		return m.Action.Cache(timeout, key.String(keys...)).Invoke(c).ToA()
+	})
+}
+
 func (m modifier) chdir(s string) carapace.Action {
 	if !strings.HasPrefix(s, "$") {
 		return m.Action.Chdir(s)
@@ -72,6 +103,20 @@ func (m modifier) chdir(s string) carapace.Action {
 	return carapace.ActionMessage("unknown macro: %#v", s)
 }
 
+// timeout cancels the action after given duration.
+// Shell macros are killed along with their process group.
+func (m modifier) timeout(s string) carapace.Action {
+	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+		timeout, err := time.ParseDuration(s)
+		if err != nil {
+			return carapace.ActionMessage(err.Error())
+		}
+
+		c.Setenv(timeoutEnv, timeout.String()) // picked up by shell macros
+		return m.Action.Timeout(timeout, carapace.ActionMessage("timed out after %v", timeout)).Invoke(c).ToA()
+	})
+}
+
 func updateEnv(a carapace.Action) carapace.Action {
 	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
 		for index, arg := range c.Parts {
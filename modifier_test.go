@@ -2,11 +2,14 @@ package spec
 
 import (
 	_ "embed"
+	"strconv"
 	"testing"
 
 	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace/pkg/assert"
 	"github.com/carapace-sh/carapace/pkg/sandbox"
 	"github.com/carapace-sh/carapace/pkg/style"
+	"github.com/spf13/cobra"
 )
 
 //go:embed example/modifier.yaml
@@ -16,6 +19,13 @@ func TestModifier(t *testing.T) {
 	sandboxSpec(t, modifierSpec)(func(s *sandbox.Sandbox) {
 		for _, command := range []string{"generic", "specific"} {
 
+			s.Run(command, "--cache", "").
+				Expect(carapace.ActionValues(
+					"one",
+					"two",
+					"three",
+				).Usage("$cache"))
+
 			s.Run(command, "--filter", "").
 				Expect(carapace.ActionValues(
 					"one",
@@ -153,6 +163,10 @@ func TestModifier(t *testing.T) {
 				).Tag("numbers").
 					Usage("$tag"))
 
+			s.Run(command, "--timeout", "").
+				Expect(carapace.ActionMessage("timed out after 100ms").
+					Usage("$timeout"))
+
 			s.Run(command, "--uniquelist", "").
 				Expect(carapace.ActionValues(
 					"one",
@@ -174,3 +188,38 @@ func TestModifier(t *testing.T) {
 		}
 	})
 }
+
+func TestModifierCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	invocations := 0
+	AddMacro("cachecounter", MacroN(func() carapace.Action {
+		return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+			invocations++
+			return carapace.ActionValues(strconv.Itoa(invocations))
+		})
+	}))
+
+	cmd := &cobra.Command{Use: "cache"}
+	complete := func(dir string, values ...string) {
+		NewAction(values).Parse(cmd).Invoke(carapace.Context{Dir: dir})
+	}
+
+	dir := t.TempDir()
+	complete(dir, "$_.cachecounter", "$cache(1h)")
+	complete(dir, "$_.cachecounter", "$cache(1h)")
+	assert.Equal(t, 1, invocations) // cache hit
+
+	complete(t.TempDir(), "$_.cachecounter", "$cache(1h)")
+	assert.Equal(t, 2, invocations) // keyed by working directory
+
+	complete(dir, "$_.cachecounter", "$cache(1h, other)")
+	assert.Equal(t, 3, invocations) // keyed by additional keys
+
+	complete(dir, "$_.cachecounter", "$cache(1h)", "other")
+	assert.Equal(t, 4, invocations) // keyed by the batch
+
+	complete(dir, "$_.cachecounter ||| $cache(1h)")
+	complete(dir, "$_.cachecounter ||| $cache(1h)", "other")
+	assert.Equal(t, 5, invocations) // keyed by the value (regardless of the batch)
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action is the completion of a flag or positional argument.
+//
+// It is either a list of values and macros or a conditional completion:
+//
+//	when:
+//	  - flag: {format: json}
+//	    then: ["$files([.json])"]
+//	  - arg: {0: deploy}
+//	    then: [staging, production]
+//	else: ["$files"]
+type Action struct {
+	Values []string
+	When   []Condition
+	Else   []string
+}
+
+// Condition is a conditional completion which applies if all given flags and positional arguments match.
+type Condition struct {
+	Flag map[string]string `yaml:"flag,omitempty" json:"flag,omitempty" jsonschema_description:"Flags with their expected value"`
+	Arg  map[int]string    `yaml:"arg,omitempty" json:"arg,omitempty" jsonschema_description:"Positional arguments by index with their expected value"`
+	Then []string          `yaml:"then" json:"then" jsonschema_description:"Completion if the condition applies"`
+}
+
+// IsConditional checks whether the action uses the `when:` form.
+func (a Action) IsConditional() bool {
+	return len(a.When) > 0 || len(a.Else) > 0
+}
+
+func (a Action) MarshalYAML() (any, error) {
+	switch {
+	case len(a.When) > 0:
+		return struct {
+			When []Condition `yaml:"when,omitempty"`
+			Else []string    `yaml:"else,omitempty"`
+		}{a.When, a.Else}, nil
+	case len(a.Else) > 0:
+		return a.Else, nil // always applies without conditions
+	default:
+		return a.Values, nil
+	}
+}
+
+func (a *Action) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		var values []string
+		if err := value.Decode(&values); err != nil {
+			return nodeError(value, "", err)
+		}
+		*a = Action{Values: values}
+
+	case yaml.MappingNode:
+		var conditional struct {
+			When []Condition `yaml:"when"`
+			Else []string    `yaml:"else"`
+		}
+		if err := value.Decode(&conditional); err != nil {
+			return nodeError(value, "", err)
+		}
+		if len(conditional.When) == 0 {
+			return nodeError(value, "", errors.New("invalid type for Action: missing when"))
+		}
+		*a = Action{When: conditional.When, Else: conditional.Else}
+
+	default:
+		return nodeError(value, "", errors.New("invalid type for Action: expected array or when"))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAction(t *testing.T) {
+	var completion struct {
+		Positional []Action `yaml:"positional"`
+	}
+	content := `positional:
+    - [one, two]
+    - when:
+        - flag: {format: json}
+          arg: {0: deploy}
+          then: [three]
+      else: [four]
+`
+	if err := yaml.Unmarshal([]byte(content), &completion); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, Action{Values: []string{"one", "two"}}, completion.Positional[0])
+	assert.Equal(t, Action{
+		When: []Condition{{
+			Flag: map[string]string{"format": "json"},
+			Arg:  map[int]string{0: "deploy"},
+			Then: []string{"three"},
+		}},
+		Else: []string{"four"},
+	}, completion.Positional[1])
+
+	m, err := yaml.Marshal(completion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `positional:
+    - - one
+      - two
+    - when:
+        - flag:
+            format: json
+          arg:
+            0: deploy
+          then:
+            - three
+      else:
+        - four
+`, string(m))
+}
+
+func TestActionInvalid(t *testing.T) {
+	var a Action
+	err := yaml.Unmarshal([]byte(`else: [one]`), &a)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	assert.Equal(t, "1:1: invalid type for Action: missing when", err.Error())
+}
+
+func TestActionElse(t *testing.T) {
+	m, err := yaml.Marshal(Action{Else: []string{"one"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "- one\n", string(m))
+
+	var a Action
+	if err := yaml.Unmarshal(m, &a); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Action{Values: []string{"one"}}, a)
+}
@@ -0,0 +1,38 @@
+package command
+
+import "strings"
+
+// Args constrains the positional arguments (those before `--`) in runnable mode.
+//
+//	args:
+//	  min: 1
+//	  max: 2
+//	  valid: true
+type Args struct {
+	None  bool `yaml:"none,omitempty" json:"none,omitempty" jsonschema_description:"Accept no positional arguments"`
+	Exact int  `yaml:"exact,omitempty" json:"exact,omitempty" jsonschema_description:"Exact amount of positional arguments"`
+	Min   int  `yaml:"min,omitempty" json:"min,omitempty" jsonschema_description:"Minimum amount of positional arguments"`
+	Max   int  `yaml:"max,omitempty" json:"max,omitempty" jsonschema_description:"Maximum amount of positional arguments"`
+	Valid bool `yaml:"valid,omitempty" json:"valid,omitempty" jsonschema_description:"Only accept the static values of the positional completion"`
+}
+
+// IsZero checks whether the arguments are unconstrained.
+func (a Args) IsZero() bool {
+	return a == Args{}
+}
+
+// StaticValues returns the values of a completion consisting only of static values (without description and style).
+func (a Action) StaticValues() ([]string, bool) {
+	if a.IsConditional() || len(a.Values) == 0 {
+		return nil, false
+	}
+
+	values := make([]string, 0, len(a.Values))
+	for _, s := range a.Values {
+		if strings.HasPrefix(s, "$") || strings.Contains(s, "${") {
+			return nil, false // macro, modifier or variable
+		}
+		values = append(values, strings.SplitN(s, "\t", 2)[0])
+	}
+	return values, true
+}
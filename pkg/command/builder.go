@@ -0,0 +1,209 @@
+package command
+
+import "errors"
+
+// Builder constructs a command programmatically.
+//
+//	cmd, err := command.NewCommand("example").
+//		Description("example command").
+//		Flag("-f, --file=", "file to read").
+//		FlagCompletion("file", "$files").
+//		Positional("one", "two").
+//		Subcommand(command.NewCommand("sub")).
+//		Build()
+type Builder struct {
+	command     Command
+	subcommands []*Builder
+	errs        []*Error
+}
+
+// NewCommand returns a builder for a command with given name.
+func NewCommand(name string) *Builder {
+	return &Builder{command: Command{Name: name}}
+}
+
+func (b *Builder) Aliases(aliases ...string) *Builder {
+	b.command.Aliases = append(b.command.Aliases, aliases...)
+	return b
+}
+
+func (b *Builder) Description(description string) *Builder {
+	b.command.Description = description
+	return b
+}
+
+func (b *Builder) Group(group string) *Builder {
+	b.command.Group = group
+	return b
+}
+
+func (b *Builder) Hidden() *Builder {
+	b.command.Hidden = true
+	return b
+}
+
+func (b *Builder) Deprecated(message string) *Builder {
+	b.command.Deprecated = message
+	return b
+}
+
+func (b *Builder) Parsing(parsing Parsing) *Builder {
+	b.command.Parsing = parsing
+	return b
+}
+
+// Flag adds a flag by its definition (e.g. `-f, --file=`).
+func (b *Builder) Flag(definition, description string) *Builder {
+	return b.addFlag("flags", definition, description, false)
+}
+
+// PersistentFlag adds a persistent flag by its definition (e.g. `-f, --file=`).
+func (b *Builder) PersistentFlag(definition, description string) *Builder {
+	return b.addFlag("persistentflags", definition, description, true)
+}
+
+func (b *Builder) addFlag(field, definition, description string, persistent bool) *Builder {
+	f, err := parseFlag(definition, description)
+	if err != nil {
+		b.errs = append(b.errs, &Error{Path: JoinPath("", field, Key(definition)), Err: err})
+		return b
+	}
+	f.Persistent = persistent
+	b.command.AddFlag(*f)
+	return b
+}
+
+func (b *Builder) ExclusiveFlags(names ...string) *Builder {
+	b.command.ExclusiveFlags = append(b.command.ExclusiveFlags, names)
+	return b
+}
+
+func (b *Builder) RequiredTogether(names ...string) *Builder {
+	b.command.RequiredTogether = append(b.command.RequiredTogether, names)
+	return b
+}
+
+func (b *Builder) OneRequired(names ...string) *Builder {
+	b.command.OneRequired = append(b.command.OneRequired, names)
+	return b
+}
+
+// Requires marks flags required by given flag.
+func (b *Builder) Requires(name string, required ...string) *Builder {
+	if b.command.Requires == nil {
+		b.command.Requires = make(map[string][]string)
+	}
+	b.command.Requires[name] = append(b.command.Requires[name], required...)
+	return b
+}
+
+func (b *Builder) Run(run Run) *Builder {
+	b.command.Run = run
+	return b
+}
+
+func (b *Builder) Args(args Args) *Builder {
+	b.command.Args = args
+	return b
+}
+
+// Alias runs given command with its arguments.
+func (b *Builder) Alias(s ...string) *Builder {
+	run, err := Alias(s...)
+	if err != nil {
+		b.errs = append(b.errs, &Error{Path: "run", Err: err})
+		return b
+	}
+	return b.Run(run)
+}
+
+// FlagCompletion sets the completion of given flag.
+func (b *Builder) FlagCompletion(name string, values ...string) *Builder {
+	return b.FlagAction(name, Action{Values: values})
+}
+
+// FlagAction sets the (possibly conditional) completion of given flag.
+func (b *Builder) FlagAction(name string, a Action) *Builder {
+	if b.command.Completion.Flag == nil {
+		b.command.Completion.Flag = make(map[string]Action)
+	}
+	b.command.Completion.Flag[name] = a
+	return b
+}
+
+// Positional adds the completion of the next positional argument.
+func (b *Builder) Positional(values ...string) *Builder {
+	return b.PositionalAction(Action{Values: values})
+}
+
+// PositionalAction adds the (possibly conditional) completion of the next positional argument.
+func (b *Builder) PositionalAction(a Action) *Builder {
+	b.command.Completion.Positional = append(b.command.Completion.Positional, a)
+	return b
+}
+
+func (b *Builder) PositionalAny(values ...string) *Builder {
+	b.command.Completion.PositionalAny = values
+	return b
+}
+
+// Dash adds the completion of the next argument after `--`.
+func (b *Builder) Dash(values ...string) *Builder {
+	b.command.Completion.Dash = append(b.command.Completion.Dash, values)
+	return b
+}
+
+func (b *Builder) DashAny(values ...string) *Builder {
+	b.command.Completion.DashAny = values
+	return b
+}
+
+func (b *Builder) Completion(completion Completion) *Builder {
+	b.command.Completion = completion
+	return b
+}
+
+func (b *Builder) Documentation(documentation Documentation) *Builder {
+	b.command.Documentation = documentation
+	return b
+}
+
+func (b *Builder) Example(command, description string) *Builder {
+	if b.command.Examples == nil {
+		b.command.Examples = make(map[string]string)
+	}
+	b.command.Examples[command] = description
+	return b
+}
+
+func (b *Builder) Subcommand(subcommands ...*Builder) *Builder {
+	b.subcommands = append(b.subcommands, subcommands...)
+	return b
+}
+
+// Build returns the command or the problems found while building and validating it.
+func (b *Builder) Build() (Command, error) {
+	c, errs := b.build("")
+	if len(errs) > 0 {
+		joined := make([]error, 0, len(errs))
+		for _, err := range errs {
+			joined = append(joined, err)
+		}
+		return c, errors.Join(joined...)
+	}
+	return c, c.Validate()
+}
+
+func (b *Builder) build(path string) (Command, []*Error) {
+	c := b.command
+	errs := make([]*Error, 0)
+	for _, err := range b.errs {
+		errs = append(errs, &Error{Path: joinPaths(path, err.Path), Err: err.Err})
+	}
+	for index, subcommand := range b.subcommands {
+		subcmd, subErrs := subcommand.build(JoinPath(path, "commands", index))
+		errs = append(errs, subErrs...)
+		c.Commands = append(c.Commands, subcmd)
+	}
+	return c, errs
+}
@@ -0,0 +1,64 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuilder(t *testing.T) {
+	cmd, err := NewCommand("example").
+		Description("example command").
+		Flag("-f, --file=", "file to read").
+		FlagCompletion("file", "$files").
+		Positional("one", "two").
+		Subcommand(NewCommand("sub").Alias("tail", "--lines", "1")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := yaml.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err = Format(m); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `name: example
+description: example command
+flags:
+  -f, --file=: file to read
+completion:
+  flag:
+    file: ["$files"]
+  positional:
+    - [one, two]
+commands:
+  - name: sub
+    run: [tail, --lines, "1"]
+`, string(m))
+}
+
+func TestBuilderInvalid(t *testing.T) {
+	_, err := NewCommand("example").
+		Subcommand(NewCommand("sub").Flag("--invalid flag", "")).
+		Build()
+	assert.Equal(t, `commands[0].flags["--invalid flag"]: flag syntax invalid: --invalid flag`, err.Error())
+
+	_, err = NewCommand("example").
+		Flag("-f, --file=", "file to read").
+		FlagCompletion("unknown", "$files").
+		ExclusiveFlags("file", "other").
+		Build()
+	assert.Equal(t, `completion.flag["unknown"]: unknown flag: "unknown"
+exclusiveflags[0][1]: unknown flag: "other"`, err.Error())
+
+	_, err = NewCommand("example").
+		Args(Args{Min: 2, Max: 1, Valid: true}).
+		Positional("$files").
+		Build()
+	assert.Equal(t, `args.min: exceeds max: 2 > 1
+args.valid: no positional completion with static values`, err.Error())
+}
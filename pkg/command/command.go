@@ -3,6 +3,8 @@ package command
 import (
 	"errors"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Command struct {
@@ -11,56 +13,137 @@ type Command struct {
 	Description string   `yaml:"description,omitempty" json:"description,omitempty" jsonschema_description:"Description of the command"`
 	Group       string   `yaml:"group,omitempty" json:"group,omitempty" jsonschema_description:"Group of the command"`
 	Hidden      bool     `yaml:"hidden,omitempty" json:"hidden,omitempty" jsonschema_description:"Hidden state of the command"`
+	Deprecated  string   `yaml:"deprecated,omitempty" json:"deprecated,omitempty" jsonschema_description:"Deprecation message of the command"`
 	Parsing     Parsing  `yaml:"parsing,omitempty" json:"parsing,omitempty" jsonschema_description:"Flag parsing mode of the command" jsonschema:"enum=interspersed,enum=non-interspersed,enum=disabled"`
 
-	Flags           FlagSet    `yaml:"flags,omitempty" json:"flags,omitempty" jsonschema_description:"Flags of the command with their description"`
-	PersistentFlags FlagSet    `yaml:"persistentflags,omitempty" json:"persistentflags,omitempty" jsonschema_description:"Persistent flags of the command with their description"`
-	ExclusiveFlags  [][]string `yaml:"exclusiveflags,omitempty" json:"exclusiveflags,omitempty" jsonschema_description:"Flags that are mutually exclusive"`
-	Run             Run        `yaml:"run,omitempty" json:"run,omitempty" jsonschema:"oneof_type=string;array" jsonschema_description:"Command or script to execute in runnable mode"`
-	Completion      struct {
-		Flag          map[string][]string `yaml:"flag,omitempty" json:"flag,omitempty" jsonschema_description:"Flag completion"`
-		Positional    [][]string          `yaml:"positional,omitempty" json:"positional,omitempty" jsonschema_description:"Positional completion"`
-		PositionalAny []string            `yaml:"positionalany,omitempty" json:"positionalany,omitempty" jsonschema_description:"Positional completion for every other position"`
-		Dash          [][]string          `yaml:"dash,omitempty" json:"dash,omitempty" jsonschema_description:"Dash completion"`
-		DashAny       []string            `yaml:"dashany,omitempty" json:"dashany,omitempty" jsonschema_description:"Dash completion of every other position"`
-	} `yaml:"completion,omitempty" json:"completion,omitzero" jsonschema_description:"Completion definition"`
-	Commands []Command `yaml:"commands,omitempty" json:"commands,omitempty" jsonschema_description:"Subcommands of the command"`
-
-	Documentation struct {
-		Command       string            `yaml:"command,omitempty" json:"command,omitempty" jsonschema_description:"Documentation of the command"`
-		Flag          map[string]string `yaml:"flag,omitempty" json:"flag,omitempty" jsonschema_description:"Documentation of flags"`
-		Positional    []string          `yaml:"positional,omitempty" json:"positional,omitempty" jsonschema_description:"Documentation of positional arguments"`
-		PositionalAny string            `yaml:"positionalany,omitempty" json:"positionalany,omitempty" jsonschema_description:"Documentation of other positional arguments"`
-		Dash          []string          `yaml:"dash,omitempty" json:"dash,omitempty" jsonschema_description:"Documentation of dash arguments"`
-		DashAny       string            `yaml:"dashany,omitempty" json:"dashany,omitempty" jsonschema_description:"Documentation of other dash arguments"`
-	} `yaml:"documentation,omitempty" json:"documentation,omitzero" jsonschema_description:"Documentation"`
-	Examples map[string]string `yaml:"examples,omitempty" json:"examples,omitempty" jsonschema_description:"Examples"`
+	Flags            FlagSet             `yaml:"flags,omitempty" json:"flags,omitempty" jsonschema_description:"Flags of the command with their description"`
+	PersistentFlags  FlagSet             `yaml:"persistentflags,omitempty" json:"persistentflags,omitempty" jsonschema_description:"Persistent flags of the command with their description"`
+	ExclusiveFlags   [][]string          `yaml:"exclusiveflags,omitempty" json:"exclusiveflags,omitempty" jsonschema_description:"Flags that are mutually exclusive"`
+	RequiredTogether [][]string          `yaml:"requiredtogether,omitempty" json:"requiredtogether,omitempty" jsonschema_description:"Flags that must be used together"`
+	OneRequired      [][]string          `yaml:"onerequired,omitempty" json:"onerequired,omitempty" jsonschema_description:"Flags of which at least one is required"`
+	Requires         map[string][]string `yaml:"requires,omitempty" json:"requires,omitempty" jsonschema_description:"Flags required by a flag"`
+	Run              Run                 `yaml:"run,omitempty" json:"run,omitempty" jsonschema:"oneof_type=string;array" jsonschema_description:"Command or script to execute in runnable mode"`
+	Args             Args                `yaml:"args,omitempty" json:"args,omitzero" jsonschema_description:"Constraints of positional arguments in runnable mode"`
+	Completion       Completion          `yaml:"completion,omitempty" json:"completion,omitzero" jsonschema_description:"Completion definition"`
+	Commands         []Command           `yaml:"commands,omitempty" json:"commands,omitempty" jsonschema_description:"Subcommands of the command"`
+
+	Documentation Documentation     `yaml:"documentation,omitempty" json:"documentation,omitzero" jsonschema_description:"Documentation"`
+	Examples      map[string]string `yaml:"examples,omitempty" json:"examples,omitempty" jsonschema_description:"Examples"`
+
+	origin *origin
+}
+
+// Completion defines the completion of flags and arguments.
+type Completion struct {
+	Flag          map[string]Action `yaml:"flag,omitempty" json:"flag,omitempty" jsonschema_description:"Flag completion"`
+	Positional    []Action          `yaml:"positional,omitempty" json:"positional,omitempty" jsonschema_description:"Positional completion"`
+	PositionalAny []string          `yaml:"positionalany,omitempty" json:"positionalany,omitempty" jsonschema_description:"Positional completion for every other position"`
+	Dash          [][]string        `yaml:"dash,omitempty" json:"dash,omitempty" jsonschema_description:"Dash completion"`
+	DashAny       []string          `yaml:"dashany,omitempty" json:"dashany,omitempty" jsonschema_description:"Dash completion of every other position"`
+}
+
+// Documentation describes the command and its flags and arguments.
+type Documentation struct {
+	Command       string            `yaml:"command,omitempty" json:"command,omitempty" jsonschema_description:"Documentation of the command"`
+	Flag          map[string]string `yaml:"flag,omitempty" json:"flag,omitempty" jsonschema_description:"Documentation of flags"`
+	Positional    []string          `yaml:"positional,omitempty" json:"positional,omitempty" jsonschema_description:"Documentation of positional arguments"`
+	PositionalAny string            `yaml:"positionalany,omitempty" json:"positionalany,omitempty" jsonschema_description:"Documentation of other positional arguments"`
+	Dash          []string          `yaml:"dash,omitempty" json:"dash,omitempty" jsonschema_description:"Documentation of dash arguments"`
+	DashAny       string            `yaml:"dashany,omitempty" json:"dashany,omitempty" jsonschema_description:"Documentation of other dash arguments"`
+}
+
+func (c *Command) UnmarshalYAML(value *yaml.Node) error {
+	type command Command // prevent recursion
+
+	if value.Kind != yaml.MappingNode {
+		return value.Decode((*command)(c))
+	}
+
+	// decode fields with custom unmarshalling separately to determine the key path of errors
+	rest := *value
+	rest.Content = make([]*yaml.Node, 0, len(value.Content))
+	var flags, persistentFlags FlagSet
+	var run Run
+	var commands []Command
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, v := value.Content[i], value.Content[i+1]
+		if _, ok, _ := refOf(v); ok && key.Value != "commands" {
+			continue // unresolved include (retained by origin for marshalling)
+		}
+
+		switch key.Value {
+		case "flags":
+			if err := v.Decode(&flags); err != nil {
+				return nodeError(v, "flags", err)
+			}
+		case "persistentflags":
+			if err := v.Decode(&persistentFlags); err != nil {
+				return nodeError(v, "persistentflags", err)
+			}
+			for name, f := range persistentFlags {
+				f.Persistent = true
+				persistentFlags[name] = f
+			}
+		case "run":
+			if err := v.Decode(&run); err != nil {
+				return nodeError(v, "run", err)
+			}
+		case "commands":
+			if v.Kind != yaml.SequenceNode {
+				rest.Content = append(rest.Content, key, v)
+				continue
+			}
+			commands = make([]Command, len(v.Content))
+			for index, subcommand := range v.Content {
+				if err := subcommand.Decode(&commands[index]); err != nil {
+					return nodeError(subcommand, JoinPath("commands", index), err)
+				}
+			}
+		default:
+			rest.Content = append(rest.Content, key, v)
+		}
+	}
+
+	if err := rest.Decode((*command)(c)); err != nil {
+		return err
+	}
+	c.Flags = flags
+	c.PersistentFlags = persistentFlags
+	c.Run = run
+	if commands != nil {
+		c.Commands = commands
+	}
+	c.origin = &origin{node: value}
+	return nil
 }
 
+// AddFlag adds given flag keyed by its name (like in an unmarshalled FlagSet), replacing an existing one with the same name.
 func (c *Command) AddFlag(f Flag) {
 	switch {
 	case f.Persistent:
 		if c.PersistentFlags == nil {
 			c.PersistentFlags = make(FlagSet)
 		}
-		c.PersistentFlags[f.format()] = f
+		c.PersistentFlags[f.Name()] = f
 
 	default:
 		if c.Flags == nil {
 			c.Flags = make(FlagSet)
 		}
-		c.Flags[f.format()] = f
+		c.Flags[f.Name()] = f
 	}
 }
 
+// Find returns the subcommand at given path (the command itself for an empty one).
+// The result points into c: modifying it modifies the subcommand of c.
 func (c *Command) Find(args []string) (*Command, error) {
 	if len(args) == 0 {
 		return c, nil
 	}
 
-	for _, subcommand := range c.Commands {
-		if strings.Split(subcommand.Name, " ")[0] == args[0] {
-			return subcommand.Find(args[1:])
+	for index := range c.Commands {
+		if strings.Split(c.Commands[index].Name, " ")[0] == args[0] {
+			return c.Commands[index].Find(args[1:])
 		}
 	}
 	return nil, errors.New("not found")
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAddFlag(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte("flags:\n  -v, --verbose: verbose output\n"), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd.AddFlag(Flag{Longhand: "verbose", Shorthand: "v", Description: "more output", Repeatable: true})
+	assert.Equal(t, FlagSet{
+		"verbose": Flag{Longhand: "verbose", Shorthand: "v", Description: "more output", Repeatable: true},
+	}, cmd.Flags)
+}
+
+func TestFind(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte("name: root\ncommands:\n  - name: sub\n    commands:\n      - name: nested\n"), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err := cmd.Find(nil); err != nil || found != &cmd {
+		t.Errorf("expected command itself: %v", err)
+	}
+
+	nested, err := cmd.Find([]string{"sub", "nested"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested.Description = "modified"
+	assert.Equal(t, "modified", cmd.Commands[0].Commands[0].Description) // aliases the subcommand
+
+	if _, err := cmd.Find([]string{"missing"}); err == nil {
+		t.Error("expected error")
+	}
+}
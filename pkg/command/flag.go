@@ -20,7 +20,14 @@ type Flag struct {
 	Required        bool
 	Persistent      bool
 
-	Nargs int
+	Nargs   int
+	Type    string
+	Default string
+	Enum    []string
+	Env     string
+
+	Deprecated          string
+	ShorthandDeprecated string
 }
 
 func (f Flag) Name() string {
@@ -30,6 +37,11 @@ func (f Flag) Name() string {
 	return f.Shorthand
 }
 
+// Definition returns the flag as used as key in a FlagSet (e.g. `-x, --example=`).
+func (f Flag) Definition() string {
+	return f.format()
+}
+
 func (f Flag) format() string {
 	var s string
 
@@ -43,7 +55,7 @@ func (f Flag) format() string {
 	if f.Longhand != "" {
 		switch {
 		case f.NameAsShorthand:
-			s += "-" + f.Shorthand
+			s += "-" + f.Longhand
 		default:
 			s += "--" + f.Longhand
 		}
@@ -22,6 +22,9 @@ func TestParseFlag(t *testing.T) {
 				t.Logf("expected: %#v", expected)
 				t.Logf("actual:   %#v", f)
 			}
+			if formatted, err := parseFlag(f.format(), description); err != nil || !reflect.DeepEqual(formatted, f) {
+				t.Errorf("format: %v", f.format())
+			}
 		})
 	}
 
@@ -2,6 +2,7 @@ package command
 
 import (
 	"errors"
+	"reflect"
 
 	"gopkg.in/yaml.v3"
 )
@@ -9,20 +10,49 @@ import (
 type FlagSet map[string]Flag
 
 type Extended struct {
-	Description string `yaml:"description,omitempty" json:"description,omitempty" jsonschema_description:"Description of the flag"`
-	Nargs       int    `yaml:"nargs,omitempty" json:"nargs,omitempty" jsonschema_description:"Amount of arguments consumed"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty" jsonschema_description:"Description of the flag"`
+	Nargs       int      `yaml:"nargs,omitempty" json:"nargs,omitempty" jsonschema_description:"Amount of arguments consumed"`
+	Type        string   `yaml:"type,omitempty" json:"type,omitempty" jsonschema_description:"Type of the flag value" jsonschema:"enum=string,enum=bool,enum=int,enum=float,enum=duration,enum=enum"`
+	Default     string   `yaml:"default,omitempty" json:"default,omitempty" jsonschema_description:"Default value of the flag"`
+	Enum        []string `yaml:"enum,omitempty" json:"enum,omitempty" jsonschema_description:"Allowed values of the flag"`
+	Env         string   `yaml:"env,omitempty" json:"env,omitempty" jsonschema_description:"Environment variable used as value when the flag is not set"`
+
+	Deprecated          string `yaml:"deprecated,omitempty" json:"deprecated,omitempty" jsonschema_description:"Deprecation message of the flag"`
+	ShorthandDeprecated string `yaml:"shorthanddeprecated,omitempty" json:"shorthanddeprecated,omitempty" jsonschema_description:"Deprecation message of the flag shorthand"`
+}
+
+func (e Extended) apply(f *Flag) {
+	f.Description = e.Description
+	f.Nargs = e.Nargs
+	f.Type = e.Type
+	f.Default = e.Default
+	f.Enum = e.Enum
+	f.Env = e.Env
+	f.Deprecated = e.Deprecated
+	f.ShorthandDeprecated = e.ShorthandDeprecated
+}
+
+func (f Flag) extended() Extended {
+	return Extended{
+		Description: f.Description,
+		Nargs:       f.Nargs,
+		Type:        f.Type,
+		Default:     f.Default,
+		Enum:        f.Enum,
+		Env:         f.Env,
+
+		Deprecated:          f.Deprecated,
+		ShorthandDeprecated: f.ShorthandDeprecated,
+	}
 }
 
 func (fs FlagSet) MarshalYAML() (any, error) {
 	m := make(map[string]any)
 
 	for _, f := range fs {
-		switch {
-		case f.Nargs != 0: // TODO other values causing extended version
-			m[f.format()] = Extended{
-				Description: f.Description,
-				Nargs:       f.Nargs,
-			}
+		switch extended := f.extended(); {
+		case !reflect.DeepEqual(extended, Extended{Description: f.Description}):
+			m[f.format()] = extended
 		default:
 			m[f.format()] = f.Description
 		}
@@ -31,33 +61,39 @@ func (fs FlagSet) MarshalYAML() (any, error) {
 }
 
 func (fs *FlagSet) UnmarshalYAML(value *yaml.Node) error {
-	m := make(map[string]any)
-	if err := value.Decode(&m); err != nil {
-		return err
+	if value.Kind != yaml.MappingNode {
+		return nodeError(value, "", errors.New("invalid type for FlagSet"))
 	}
 
 	flagSet := make(FlagSet)
-	for k, v := range m {
-		switch v := v.(type) {
-		case string:
-			f, err := parseFlag(k, v)
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		k, v := value.Content[i], value.Content[i+1]
+		path := JoinPath("", Key(k.Value))
+
+		switch {
+		case v.Kind == yaml.ScalarNode && v.ShortTag() == "!!str":
+			f, err := parseFlag(k.Value, v.Value)
 			if err != nil {
-				return err
+				return nodeError(k, path, err)
 			}
 			flagSet[f.Name()] = *f // TODO ref?
 
-		case map[string]any:
-			f, err := parseFlag(k, "")
+		case v.Kind == yaml.MappingNode:
+			f, err := parseFlag(k.Value, "")
 			if err != nil {
-				return err
+				return nodeError(k, path, err)
+			}
+
+			var extended Extended
+			if err := v.Decode(&extended); err != nil {
+				return nodeError(v, path, err)
 			}
-			f.Description, _ = v["description"].(string)
-			f.Nargs, _ = v["nargs"].(int)
+			extended.apply(f)
 
 			flagSet[f.Name()] = *f // TODO ref?
 
 		default:
-			return errors.New("invalid type for FlagSet")
+			return nodeError(v, path, errors.New("invalid type for FlagSet"))
 		}
 	}
 	*fs = flagSet
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format rewrites a spec into its canonical layout while retaining comments.
+//
+//   - fields are ordered as in the struct definition
+//   - flags are sorted by name and have their modifiers normalized
+//   - aliases are in flow style
+//   - indentation is two spaces
+func Format(content []byte) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return nil, errors.New("empty document")
+	}
+
+	var cmd Command
+	if err := document.Content[0].Decode(&cmd); err != nil {
+		return nil, err
+	}
+
+	v, err := cmd.MarshalYAML()
+	if err != nil {
+		return nil, err
+	}
+	node := v.(*yaml.Node)
+	canonicalize(node, reflect.TypeOf(cmd))
+	document.Content[0] = node
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalize orders the keys of given mapping node by the fields of given struct type.
+func canonicalize(node *yaml.Node, t reflect.Type) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	if _, ok, _ := refOf(node); ok {
+		return
+	}
+
+	fields := yamlFields(t)
+	sortMapping(node, func(key string) int {
+		if index, ok := fields[key]; ok {
+			return index
+		}
+		return t.NumField() // unknown keys last
+	})
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		index, ok := fields[key]
+		if !ok {
+			continue
+		}
+
+		switch field := t.Field(index); {
+		case key == "aliases" && value.Kind == yaml.SequenceNode:
+			value.Style = yaml.FlowStyle
+		case field.Type == reflect.TypeOf(FlagSet{}):
+			canonicalizeFlags(value)
+		case field.Type == reflect.TypeOf([]Command{}) && value.Kind == yaml.SequenceNode:
+			for _, subcommand := range value.Content {
+				canonicalize(subcommand, t)
+			}
+		case field.Type.Kind() == reflect.Struct:
+			canonicalize(value, field.Type)
+		}
+	}
+}
+
+// canonicalizeFlags normalizes the modifiers of given flags and sorts them by name.
+func canonicalizeFlags(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	if _, ok, _ := refOf(node); ok {
+		return
+	}
+
+	names := make(map[string]string)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if f, err := parseFlag(node.Content[i].Value, ""); err == nil {
+			node.Content[i].Value = f.format()
+			names[f.format()] = f.Name()
+		}
+		canonicalize(node.Content[i+1], reflect.TypeOf(Extended{}))
+	}
+
+	keys := make([]string, 0, len(names))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	slices.SortStableFunc(keys, func(a, b string) int {
+		return strings.Compare(names[a], names[b])
+	})
+	sortMapping(node, func(key string) int {
+		return slices.Index(keys, key)
+	})
+}
+
+// sortMapping sorts the key/value pairs of given mapping node by the rank of their key.
+func sortMapping(node *yaml.Node, rank func(key string) int) {
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+
+	slices.SortStableFunc(pairs, func(a, b [2]*yaml.Node) int {
+		return rank(a[0].Value) - rank(b[0].Value)
+	})
+
+	node.Content = node.Content[:0]
+	for _, pair := range pairs {
+		node.Content = append(node.Content, pair[0], pair[1])
+	}
+}
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+)
+
+func TestFormat(t *testing.T) {
+	content := `# yaml-language-server: $schema=https://carapace.sh/schemas/command.json
+name: root
+commands:
+    - description: sub command
+      name: sub
+    - $ref: included.yaml
+completion:
+    positional:
+        - [one, two] # first
+    flag:
+        zeta: ["$files"]
+flags:
+    --zeta&=: zeta flag # last
+    # about alpha
+    -a, --alpha!*: alpha flag
+    -b: {nargs: 2, description: b flag}
+aliases:
+    - r
+description: root command
+`
+
+	formatted, err := Format([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# yaml-language-server: $schema=https://carapace.sh/schemas/command.json
+name: root
+aliases: [r]
+description: root command
+flags:
+  # about alpha
+  -a, --alpha*!: alpha flag
+  -b: {description: b flag, nargs: 2}
+  --zeta=&: zeta flag # last
+completion:
+  flag:
+    zeta: ["$files"]
+  positional:
+    - [one, two] # first
+commands:
+  - name: sub
+    description: sub command
+  - $ref: included.yaml
+`
+	assert.Equal(t, expected, string(formatted))
+
+	formatted, err = Format(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, string(formatted))
+}
@@ -0,0 +1,174 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RefKey is the key of an include (`{$ref: path/to/file.yaml}`).
+const RefKey = "$ref"
+
+// Load reads the spec at given path and resolves includes.
+//
+// Entries of `commands` as well as `flags`, `persistentflags` and `completion`
+// can be replaced by a mapping with a single `$ref` key which is resolved relative to the including file.
+func Load(path string) (*Command, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &loader{files: make(map[*yaml.Node]string)}
+	var cmd Command
+	node, err := l.load(abs, &cmd)
+	if err != nil {
+		return nil, err
+	}
+	cmd.origin = &origin{node: node, file: abs, files: l.files}
+	return &cmd, nil
+}
+
+// Files returns the spec file, the files it includes and applied overlays (empty if not loaded from a file).
+func (c Command) Files() []string {
+	if c.origin == nil || c.origin.file == "" {
+		return nil
+	}
+
+	files := []string{c.origin.file}
+	for _, file := range c.origin.files {
+		if !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+	slices.Sort(files[1:])
+	for _, file := range c.origin.overlays {
+		if !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+type loader struct {
+	stack []string              // files currently being loaded
+	files map[*yaml.Node]string // included files by their node
+}
+
+// load reads given file, resolves includes and decodes it into v.
+func (l *loader) load(path string, v any) (*yaml.Node, error) {
+	if slices.Contains(l.stack, path) {
+		return nil, fmt.Errorf("cyclic %v: %v", RefKey, strings.Join(append(l.stack, path), " -> "))
+	}
+	l.stack = append(l.stack, path)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return nil, fmt.Errorf("%v: empty document", path)
+	}
+
+	node := document.Content[0]
+	if _, ok := v.(*Command); ok {
+		if err := l.resolveCommand(path, node); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := node.Decode(v); err != nil {
+		return nil, fileError(path, err)
+	}
+	return node, nil
+}
+
+func (l *loader) resolveCommand(file string, node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return nil // let decoding handle the error
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "commands":
+			if value.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, subcommand := range value.Content {
+				if err := l.resolve(file, subcommand, new(Command)); err != nil {
+					return err
+				}
+			}
+		case "flags", "persistentflags":
+			if err := l.resolve(file, value, new(FlagSet)); err != nil {
+				return err
+			}
+		case "completion":
+			if err := l.resolve(file, value, &new(Command).Completion); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve replaces given node in place if it is an include (decoded into v for validation).
+func (l *loader) resolve(file string, node *yaml.Node, v any) error {
+	position := Position{File: file, Line: node.Line, Column: node.Column}
+	ref, ok, err := refOf(node)
+	if err != nil {
+		return &Error{Position: position, Err: err}
+	}
+
+	if !ok {
+		if _, ok := v.(*Command); ok {
+			return l.resolveCommand(file, node)
+		}
+		return nil
+	}
+
+	if !filepath.IsAbs(ref) {
+		ref = filepath.Join(filepath.Dir(file), ref)
+	}
+
+	included, err := l.load(filepath.Clean(ref), v)
+	if err != nil {
+		return fmt.Errorf("%v: %w", position, err)
+	}
+	*node = *included
+	l.files[node] = filepath.Clean(ref)
+	return nil
+}
+
+// refOf returns the path of an include.
+func refOf(node *yaml.Node) (string, bool, error) {
+	if node.Kind != yaml.MappingNode {
+		return "", false, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != RefKey {
+			continue
+		}
+		switch {
+		case len(node.Content) != 2:
+			return "", false, fmt.Errorf("%v must not have sibling keys", RefKey)
+		case node.Content[i+1].Kind != yaml.ScalarNode || node.Content[i+1].Value == "":
+			return "", false, errors.New("invalid " + RefKey)
+		}
+		return node.Content[i+1].Value, true, nil
+	}
+	return "", false, nil
+}
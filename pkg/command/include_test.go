@@ -0,0 +1,102 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.yaml": `name: root
+persistentflags:
+  $ref: shared/flags.yaml
+commands:
+  - $ref: sub/sub.yaml
+  - name: inline
+`,
+		"shared/flags.yaml": `-v, --verbose: verbose output`,
+		"sub/sub.yaml": `name: sub
+completion:
+  $ref: completion.yaml
+`,
+		"sub/completion.yaml": `positional:
+  - [one, two]
+`,
+	})
+
+	cmd, err := Load(filepath.Join(dir, "root.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cmd.PersistentFlags["verbose"]; !ok {
+		t.Errorf("missing persistent flag: %#v", cmd.PersistentFlags)
+	}
+
+	if len(cmd.Commands) != 2 || cmd.Commands[0].Name != "sub" || cmd.Commands[1].Name != "inline" {
+		t.Fatalf("unexpected subcommands: %#v", cmd.Commands)
+	}
+
+	if positional := cmd.Commands[0].Completion.Positional; len(positional) != 1 || strings.Join(positional[0].Values, ",") != "one,two" {
+		t.Errorf("unexpected positional completion: %#v", positional)
+	}
+
+	expected := []string{"root.yaml", "shared/flags.yaml", "sub/completion.yaml", "sub/sub.yaml"}
+	for index := range expected {
+		expected[index] = filepath.Join(dir, expected[index])
+	}
+	if files := cmd.Files(); strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected files: %#v", files)
+	}
+}
+
+func TestLoadCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": `name: a
+commands:
+  - $ref: b.yaml
+`,
+		"b.yaml": `name: b
+commands:
+  - $ref: a.yaml
+`,
+	})
+
+	_, err := Load(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "cyclic $ref") {
+		t.Fatalf("expected cycle error: %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), filepath.Join(dir, "a.yaml")+":3:5: ") {
+		t.Errorf("expected including file in error: %v", err)
+	}
+}
+
+func TestLoadSiblingKeys(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.yaml": `name: root
+commands:
+  - $ref: sub.yaml
+    name: other
+`,
+	})
+
+	if _, err := Load(filepath.Join(dir, "root.yaml")); err == nil || !strings.Contains(err.Error(), "must not have sibling keys") {
+		t.Fatalf("expected sibling key error: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package command
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalYAML encodes the command.
+//
+// For commands that were unmarshalled from YAML the source is retained as far as possible:
+// keys keep their order, comments and styles are preserved and unchanged values are emitted verbatim.
+func (c Command) MarshalYAML() (any, error) {
+	type command Command // prevent recursion
+
+	if c.origin != nil {
+		if _, ok, _ := refOf(c.origin.node); ok {
+			return c.origin.node, nil // unresolved include
+		}
+	}
+
+	plain := command(c)
+	plain.Commands = nil // encoded separately as node encoding drops comments
+
+	node := &yaml.Node{}
+	if err := node.Encode(plain); err != nil {
+		return nil, err
+	}
+	if c.origin == nil {
+		if err := flowStyle(node); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.Commands) > 0 {
+		commands := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, subcommand := range c.Commands {
+			v, err := subcommand.MarshalYAML()
+			if err != nil {
+				return nil, err
+			}
+			commands.Content = append(commands.Content, v.(*yaml.Node))
+		}
+
+		index := len(node.Content)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if slices.Contains([]string{"documentation", "examples"}, node.Content[i].Value) {
+				index = i // keep field order
+				break
+			}
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "commands"}
+		node.Content = slices.Insert(node.Content, index, key, commands)
+	}
+
+	if c.origin == nil {
+		return node, nil
+	}
+	return c.merge(node, c.origin.node), nil
+}
+
+// flowStyle applies the style commonly used in specs to a generated node:
+// lists of scalars (like completion values) and aliases are in flow style with macros quoted.
+func flowStyle(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key, value := node.Content[i], node.Content[i+1]; key.Value == "run" && Run(value.Value).Type() == "alias" {
+				var alias yaml.Node
+				if err := yaml.Unmarshal([]byte(value.Value), &alias); err != nil {
+					return err
+				}
+				node.Content[i+1] = alias.Content[0]
+			}
+		}
+	}
+
+	scalars := node.Kind == yaml.SequenceNode
+	for _, child := range node.Content {
+		if err := flowStyle(child); err != nil {
+			return err
+		}
+		scalars = scalars && child.Kind == yaml.ScalarNode
+	}
+	if scalars {
+		node.Style |= yaml.FlowStyle
+		for _, child := range node.Content {
+			if strings.HasPrefix(child.Value, "$") {
+				child.Style = yaml.DoubleQuotedStyle // quote macros
+			}
+		}
+	}
+	return nil
+}
+
+// merge applies key order, comments and styles of the original node to the generated one.
+// Fields which decode to the current value are retained verbatim.
+func (c Command) merge(generated, original *yaml.Node) *yaml.Node {
+	if generated.Kind != yaml.MappingNode || original.Kind != yaml.MappingNode {
+		return generated
+	}
+	copyComments(generated, original)
+
+	for i := 0; i+1 < len(generated.Content); i += 2 {
+		if key := generated.Content[i].Value; key != "commands" && indexOf(original, key) < 0 {
+			flowStyle(generated.Content[i+1]) // added field (e.g. by an overlay)
+		}
+	}
+
+	fields := yamlFields(reflect.TypeOf(c))
+	return mergeMapping(generated, original, func(key string, generated, original *yaml.Node) *yaml.Node {
+		index, ok := fields[key]
+		switch {
+		case key == "commands":
+			return mergeCommands(generated, original)
+		case !ok:
+			return merge(generated, original)
+		}
+
+		// decode the field on its own to compare it with the current value
+		var decoded Command
+		single := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, original}}
+		if err := single.Decode(&decoded); err == nil &&
+			reflect.DeepEqual(reflect.ValueOf(decoded).Field(index).Interface(), reflect.ValueOf(c).Field(index).Interface()) {
+			return original
+		}
+		return merge(generated, original)
+	})
+}
+
+// mergeCommands applies comments and styles of the original subcommands to the generated ones.
+// Subcommands are paired by name as they might have been added, removed or reordered (e.g. by an overlay).
+func mergeCommands(generated, original *yaml.Node) *yaml.Node {
+	if generated.Kind != yaml.SequenceNode || original.Kind != yaml.SequenceNode {
+		return generated
+	}
+	copyComments(generated, original)
+	generated.Style = original.Style
+
+	name := func(node *yaml.Node) string {
+		if index := indexOf(node, "name"); index >= 0 {
+			return strings.Split(node.Content[index+1].Value, " ")[0]
+		}
+		return ""
+	}
+	for index, item := range generated.Content {
+		for _, originalItem := range original.Content {
+			if _, ok, _ := refOf(originalItem); !ok && name(item) != "" && name(item) == name(originalItem) {
+				generated.Content[index] = merge(item, originalItem)
+				break
+			}
+		}
+	}
+	return generated
+}
+
+// merge applies key order, comments and styles of the original node to the generated one.
+func merge(generated, original *yaml.Node) *yaml.Node {
+	if generated.Kind != original.Kind {
+		return generated
+	}
+
+	switch generated.Kind {
+	case yaml.ScalarNode:
+		if generated.Value == original.Value && generated.ShortTag() == original.ShortTag() {
+			return original
+		}
+		copyComments(generated, original)
+		return generated
+
+	case yaml.SequenceNode:
+		copyComments(generated, original)
+		generated.Style = original.Style
+		for index, item := range generated.Content {
+			if index < len(original.Content) {
+				generated.Content[index] = merge(item, original.Content[index])
+			}
+		}
+		return generated
+
+	case yaml.MappingNode:
+		copyComments(generated, original)
+		return mergeMapping(generated, original, func(_ string, generated, original *yaml.Node) *yaml.Node {
+			return merge(generated, original)
+		})
+
+	default:
+		return generated
+	}
+}
+
+// mergeMapping orders the keys of the generated node as in the original one.
+// Keys missing in the original are appended, keys missing in the generated are dropped (except for includes).
+func mergeMapping(generated, original *yaml.Node, f func(key string, generated, original *yaml.Node) *yaml.Node) *yaml.Node {
+	content := make([]*yaml.Node, 0, len(generated.Content))
+	used := make(map[int]bool)
+	for i := 0; i+1 < len(original.Content); i += 2 {
+		key, value := original.Content[i], original.Content[i+1]
+
+		index := indexOf(generated, key.Value)
+		switch {
+		case index < 0:
+			if _, ok, _ := refOf(value); ok {
+				content = append(content, key, value) // unresolved include
+			}
+		case used[index]:
+			continue
+		default:
+			used[index] = true
+			generatedKey := generated.Content[index]
+			if generatedKey.Value != key.Value {
+				generatedKey.Value = key.Value // equivalent flag with modifiers in a different order
+			}
+			copyComments(generatedKey, key)
+			generatedKey.Style = key.Style
+			content = append(content, generatedKey, f(key.Value, generated.Content[index+1], value))
+		}
+	}
+
+	for i := 0; i+1 < len(generated.Content); i += 2 {
+		if !used[i] {
+			content = append(content, generated.Content[i], generated.Content[i+1])
+		}
+	}
+	generated.Content = content
+	generated.Style = original.Style
+	return generated
+}
+
+// indexOf returns the index of given key within a mapping node.
+func indexOf(node *yaml.Node, key string) int {
+	normalize := func(s string) string {
+		if f, err := parseFlag(s, ""); err == nil {
+			return f.format() // modifiers might be in a different order
+		}
+		return s
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for _, f := range []func(string) string{nil, normalize} {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			switch {
+			case f == nil && node.Content[i].Value == key:
+				return i
+			case f != nil && f(node.Content[i].Value) == f(key):
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// yamlFields returns the field index by yaml key.
+func yamlFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]; name != "" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+func copyComments(generated, original *yaml.Node) {
+	generated.HeadComment = original.HeadComment
+	generated.LineComment = original.LineComment
+	generated.FootComment = original.FootComment
+}
@@ -0,0 +1,131 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const roundtrip = `# head comment
+name: root
+description: root command # line comment
+aliases: [alias1, alias2]
+persistentflags:
+    --persistent?: persistent flag
+flags:
+    # flag comment
+    -x, --extended=: {description: extended flag, nargs: 2}
+    -b, --bool!*: bool flag
+run: [tail, --lines, '${NUM:-1}']
+documentation:
+    # documentation comment
+    command: |
+        some documentation
+completion:
+    flag:
+        extended: ["$files"]
+    positional:
+        - [one, two] # first
+commands:
+    - name: sub
+      flags:
+        --sub: sub flag
+    - $ref: included.yaml
+examples:
+    root --bool: example
+`
+
+func TestMarshalRoundtrip(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte(roundtrip), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := yaml.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, roundtrip, string(m))
+}
+
+func TestMarshalModified(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte(roundtrip), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd.Description = "modified"
+	cmd.AddFlag(Flag{Longhand: "added", Description: "added flag", Hidden: true})
+	cmd.Completion.Positional[0].Values = append(cmd.Completion.Positional[0].Values, "three")
+	cmd.Commands[0].Flags["sub"] = Flag{Longhand: "sub", Description: "modified sub flag"}
+	cmd.Examples = nil
+
+	m, err := yaml.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `# head comment
+name: root
+description: modified # line comment
+aliases: [alias1, alias2]
+persistentflags:
+    --persistent?: persistent flag
+flags:
+    # flag comment
+    -x, --extended=: {description: extended flag, nargs: 2}
+    -b, --bool!*: bool flag
+    --added&: added flag
+run: [tail, --lines, '${NUM:-1}']
+documentation:
+    # documentation comment
+    command: |
+        some documentation
+completion:
+    flag:
+        extended: ["$files"]
+    positional:
+        - [one, two, three] # first
+commands:
+    - name: sub
+      flags:
+        --sub: modified sub flag
+    - $ref: included.yaml
+`, string(m))
+}
+
+func TestMarshalNew(t *testing.T) {
+	cmd := Command{Name: "root", Description: "root command"}
+	cmd.AddFlag(Flag{Longhand: "b", Description: "b flag", Nargs: 2})
+	cmd.AddFlag(Flag{Longhand: "a", Description: "a flag", Persistent: true})
+	cmd.AddFlag(Flag{Longhand: "c", Description: "c flag", Value: true})
+	cmd.Commands = []Command{{Name: "sub"}}
+	cmd.Examples = map[string]string{"root --b": "example"}
+
+	m, err := yaml.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `name: root
+description: root command
+flags:
+    --b:
+        description: b flag
+        nargs: 2
+    --c=: c flag
+persistentflags:
+    --a: a flag
+commands:
+    - name: sub
+examples:
+    root --b: example
+`, string(m))
+
+	var actual Command
+	if err := yaml.Unmarshal(m, &actual); err != nil {
+		t.Fatal(err)
+	}
+	actual.origin = nil
+	actual.Commands[0].origin = nil
+	assert.Equal(t, cmd, actual)
+}
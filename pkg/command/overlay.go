@@ -0,0 +1,338 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Operation string
+
+const (
+	ADD     Operation = "add"     // add flags, subcommands, entries and values (fails if they already exist)
+	REPLACE Operation = "replace" // replace flags, subcommands, entries and values (fails if they don't exist)
+	REMOVE  Operation = "remove"  // remove flags, subcommands, entries and values (the command itself if empty)
+)
+
+// Overlay patches the command at given path of a base spec.
+//
+//	# example.overlay.yaml
+//	- path: [sub]
+//	  operation: add
+//	  command:
+//	    flags:
+//	      --extra: an extra flag
+type Overlay struct {
+	Path      []string  `yaml:"path,omitempty" json:"path,omitempty" jsonschema_description:"Names of the subcommands leading to the patched command (empty for the root command)"`
+	Operation Operation `yaml:"operation" json:"operation" jsonschema_description:"Operation to apply" jsonschema:"enum=add,enum=replace,enum=remove"`
+	Command   Command   `yaml:"command,omitempty" json:"command,omitzero" jsonschema_description:"Partial command with the fields to add, replace or remove"`
+
+	file string
+}
+
+// LoadOverlays reads the overlays at given path.
+func LoadOverlays(path string) ([]Overlay, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	var overlays []Overlay
+	if err := yaml.Unmarshal(content, &overlays); err != nil {
+		return nil, fileError(abs, err)
+	}
+	for index := range overlays {
+		overlays[index].file = abs
+	}
+	return overlays, nil
+}
+
+// OverlayFiles returns the overlays of the spec which exist in the order they are applied:
+// `<file>.overlay.yaml` alongside the spec followed by `<UserConfigDir>/carapace-spec/overlays/<name>.yaml`
+// (by the name of the command as specs for the same command might be located anywhere).
+// It is empty if the spec was not loaded from a file.
+func (c Command) OverlayFiles() ([]string, error) {
+	if c.origin == nil || c.origin.file == "" {
+		return nil, nil
+	}
+
+	file := c.origin.file
+	candidates := []string{strings.TrimSuffix(file, filepath.Ext(file)) + ".overlay.yaml"}
+	if name := strings.Fields(c.Name + " ")[0]; name != "" && filepath.Base(name) == name {
+		if dir, err := os.UserConfigDir(); err == nil {
+			candidates = append(candidates, filepath.Join(dir, "carapace-spec", "overlays", name+".yaml"))
+		}
+	}
+
+	files := make([]string, 0)
+	for _, candidate := range candidates {
+		switch _, err := os.Stat(candidate); {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			files = append(files, candidate)
+		}
+	}
+	return files, nil
+}
+
+// LoadWithOverlays reads the spec at given path and applies its overlays (see OverlayFiles).
+//
+// Overlay files which fail to load or apply are skipped as a whole.
+// Their errors are returned as warnings so that a broken overlay doesn't break the spec.
+func LoadWithOverlays(path string) (*Command, []error, error) {
+	cmd, err := Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files, err := cmd.OverlayFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	warnings := make([]error, 0)
+	for _, file := range files {
+		overlays, err := LoadOverlays(file)
+		if err != nil {
+			warnings = append(warnings, err)
+			continue
+		}
+
+		merged, err := cmd.Apply(overlays...)
+		if err != nil {
+			warnings = append(warnings, err)
+			continue
+		}
+		cmd = &merged
+	}
+	return cmd, warnings, nil
+}
+
+// Apply returns the command with given overlays applied in order (the command itself is left unchanged).
+// Overlays loaded from a file are included in Files.
+func (c Command) Apply(overlays ...Overlay) (Command, error) {
+	errs := make([]error, 0)
+	for index, o := range overlays {
+		p := &patcher{operation: o.Operation}
+		p.overlay(&c, o)
+
+		for _, err := range p.errs {
+			if path, ok := strings.CutPrefix(err.Path, "command"); ok {
+				err.Position = o.Command.Position(strings.TrimPrefix(path, "."))
+			}
+			err.Position.File = o.file
+			err.Path = joinPaths(JoinPath("", index), err.Path)
+			errs = append(errs, err)
+		}
+
+		if c.origin != nil && o.file != "" && !slices.Contains(c.origin.overlays, o.file) {
+			origin := *c.origin
+			origin.overlays = append(slices.Clip(origin.overlays), o.file)
+			c.origin = &origin
+		}
+	}
+	return c, errors.Join(errs...)
+}
+
+// patcher applies an overlay and collects the problems by their key path within it.
+type patcher struct {
+	operation Operation
+	errs      []*Error
+}
+
+func (p *patcher) report(path, format string, a ...any) {
+	p.errs = append(p.errs, &Error{Path: path, Err: fmt.Errorf(format, a...)})
+}
+
+func (p *patcher) overlay(c *Command, o Overlay) {
+	switch o.Operation {
+	case ADD, REPLACE, REMOVE:
+	default:
+		p.report("operation", "unknown operation: %#v", o.Operation)
+		return
+	}
+
+	if o.Operation == REMOVE && isEmpty(o.Command) {
+		if len(o.Path) == 0 {
+			p.report("path", "root command can't be removed")
+			return
+		}
+		if parent := p.target(c, o.Path[:len(o.Path)-1]); parent != nil {
+			last := o.Path[len(o.Path)-1]
+			if subcommand, err := parent.Find([]string{last}); err != nil {
+				p.report(JoinPath("path", len(o.Path)-1), "unknown command: %#v", last)
+			} else {
+				p.commands("path", parent, []Command{*subcommand})
+			}
+		}
+		return
+	}
+
+	if target := p.target(c, o.Path); target != nil {
+		p.command("command", target, o.Command)
+	}
+}
+
+// target returns the command at given path with the subcommands along it copied to keep the original unchanged.
+func (p *patcher) target(c *Command, path []string) *Command {
+	for index, name := range path {
+		c.Commands = slices.Clone(c.Commands)
+		subcommand, err := c.Find([]string{name})
+		if err != nil {
+			p.report(JoinPath("path", index), "unknown command: %#v", name)
+			return nil
+		}
+		c = subcommand
+	}
+	c.Commands = slices.Clone(c.Commands)
+	return c
+}
+
+func (p *patcher) command(path string, target *Command, patch Command) {
+	t, v := reflect.ValueOf(target).Elem(), reflect.ValueOf(patch)
+	for index := range t.NumField() {
+		switch name := yamlName(t.Type().Field(index)); name {
+		case "":
+		case "commands":
+			p.commands(JoinPath(path, name), target, patch.Commands)
+		default:
+			p.value(JoinPath(path, name), t.Field(index), v.Field(index))
+		}
+	}
+}
+
+// commands adds, replaces or removes subcommands by their name.
+func (p *patcher) commands(path string, target *Command, patch []Command) {
+	for index, subcommand := range patch {
+		name := strings.Split(subcommand.Name, " ")[0]
+		i := slices.IndexFunc(target.Commands, func(c Command) bool { return strings.Split(c.Name, " ")[0] == name })
+
+		switch {
+		case name == "":
+			p.report(JoinPath(path, index, "name"), "missing name")
+		case p.operation == ADD && i >= 0:
+			p.report(JoinPath(path, index, "name"), "command already exists: %#v", name)
+		case p.operation != ADD && i < 0:
+			p.report(JoinPath(path, index, "name"), "unknown command: %#v", name)
+		case p.operation == ADD:
+			target.Commands = append(slices.Clip(target.Commands), subcommand)
+		case p.operation == REPLACE:
+			target.Commands = slices.Clone(target.Commands)
+			target.Commands[i] = subcommand
+		default:
+			target.Commands = slices.Delete(slices.Clone(target.Commands), i, i+1)
+		}
+	}
+}
+
+// value applies a non-zero field of the patch to the target.
+//
+// Maps (like flags) are patched by key and slices (like aliases) by element, other values are set or cleared.
+func (p *patcher) value(path string, target, patch reflect.Value) {
+	if patch.IsZero() {
+		return
+	}
+
+	switch target.Kind() {
+	case reflect.Struct:
+		for index := range target.NumField() {
+			if name := yamlName(target.Type().Field(index)); name != "" {
+				p.value(JoinPath(path, name), target.Field(index), patch.Field(index))
+			}
+		}
+
+	case reflect.Map:
+		patched := reflect.MakeMap(target.Type())
+		for iter := target.MapRange(); iter.Next(); {
+			patched.SetMapIndex(iter.Key(), iter.Value())
+		}
+
+		keys := patch.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, key := range keys {
+			exists := patched.MapIndex(key).IsValid()
+			switch {
+			case p.operation == ADD && exists:
+				p.report(JoinPath(path, Key(key.String())), "already exists")
+			case p.operation != ADD && !exists:
+				p.report(JoinPath(path, Key(key.String())), "not found")
+			case p.operation == REMOVE:
+				patched.SetMapIndex(key, reflect.Value{})
+			default:
+				patched.SetMapIndex(key, patch.MapIndex(key))
+			}
+		}
+		if patched.Len() == 0 {
+			patched = reflect.Zero(target.Type())
+		}
+		target.Set(patched)
+
+	case reflect.Slice:
+		switch p.operation {
+		case ADD:
+			target.Set(reflect.AppendSlice(reflect.AppendSlice(reflect.MakeSlice(target.Type(), 0, target.Len()+patch.Len()), target), patch))
+		case REPLACE:
+			target.Set(patch)
+		case REMOVE:
+			patched := reflect.MakeSlice(target.Type(), 0, target.Len())
+			removed := make([]bool, patch.Len())
+			for i := 0; i < target.Len(); i++ {
+				index := -1
+				for j := 0; j < patch.Len(); j++ {
+					if reflect.DeepEqual(target.Index(i).Interface(), patch.Index(j).Interface()) {
+						index = j
+						break
+					}
+				}
+				if index < 0 {
+					patched = reflect.Append(patched, target.Index(i))
+				} else {
+					removed[index] = true
+				}
+			}
+			for j, ok := range removed {
+				if !ok {
+					p.report(JoinPath(path, j), "not found")
+				}
+			}
+			if patched.Len() == 0 {
+				patched = reflect.Zero(target.Type())
+			}
+			target.Set(patched)
+		}
+
+	default:
+		switch {
+		case p.operation == ADD && !target.IsZero() && !target.Equal(patch):
+			p.report(path, "already set (use replace)")
+		case p.operation == REMOVE:
+			target.Set(reflect.Zero(target.Type()))
+		default:
+			target.Set(patch)
+		}
+	}
+}
+
+func yamlName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
+}
+
+// isEmpty checks whether no field of the command is set.
+func isEmpty(c Command) bool {
+	c.origin = nil
+	return reflect.ValueOf(c).IsZero()
+}
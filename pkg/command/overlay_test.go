@@ -0,0 +1,197 @@
+package command
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestApply(t *testing.T) {
+	var base Command
+	if err := yaml.Unmarshal([]byte(`name: root
+flags:
+  -o, --old: old flag
+commands:
+  - name: sub
+    aliases: [s]
+    completion:
+      positional:
+        - [one, two]
+  - name: legacy
+`), &base); err != nil {
+		t.Fatal(err)
+	}
+
+	var overlays []Overlay
+	if err := yaml.Unmarshal([]byte(`- operation: add
+  command:
+    flags:
+      -n, --new: new flag
+- path: [sub]
+  operation: replace
+  command:
+    description: replaced
+    completion:
+      positional:
+        - [three]
+- path: [sub]
+  operation: remove
+  command:
+    aliases: [s]
+- path: [legacy]
+  operation: remove
+- operation: remove
+  command:
+    flags:
+      --old: ""
+`), &overlays); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := base.Apply(overlays...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := yaml.Marshal(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `name: root
+flags:
+    -n, --new: new flag
+commands:
+    - name: sub
+      completion:
+        positional:
+            - [three]
+      description: replaced
+`, string(m))
+
+	if _, ok := base.Flags["old"]; !ok || len(base.Commands) != 2 || len(base.Commands[0].Aliases) != 1 {
+		t.Error("base must not be changed")
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	var overlays []Overlay
+	if err := yaml.Unmarshal([]byte(`- operation: add
+  command:
+    description: already set
+    flags:
+      --old: duplicate
+- path: [missing]
+  operation: replace
+- operation: remove
+- operation: rename
+`), &overlays); err != nil {
+		t.Fatal(err)
+	}
+
+	base := Command{Name: "root", Description: "root command", Flags: FlagSet{"old": {Longhand: "old"}}}
+	_, err := base.Apply(overlays...)
+	assert.Equal(t, `3:5: [0].command.description: already set (use replace)
+4:5: [0].command.flags["old"]: already exists
+[1].path[0]: unknown command: "missing"
+[2].path: root command can't be removed
+[3].operation: unknown operation: "rename"`, err.Error())
+}
+
+func TestLoadWithOverlays(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tool-v1.yaml": "name: tool\n",
+		"tool-v1.overlay.yaml": `- operation: add
+  command:
+    description: from overlay
+`,
+		"config/carapace-spec/overlays/tool.yaml": `- operation: replace
+  command:
+    description: from config
+`,
+	})
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+
+	cmd, warnings, err := LoadWithOverlays(filepath.Join(dir, "tool-v1.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(warnings))
+	assert.Equal(t, "from config", cmd.Description)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "tool-v1.yaml"),
+		filepath.Join(dir, "tool-v1.overlay.yaml"),
+		filepath.Join(dir, "config/carapace-spec/overlays/tool.yaml"),
+	}, cmd.Files())
+}
+
+func TestLoadWithOverlaysBroken(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tool.yaml": "name: tool\ndescription: base\n",
+		"tool.overlay.yaml": `- operation: add
+  command:
+    description: conflicting
+`,
+		"config/carapace-spec/overlays/tool.yaml": `- operation: add
+  command:
+    flags:
+      --extra: extra flag
+`,
+	})
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+
+	cmd, warnings, err := LoadWithOverlays(filepath.Join(dir, "tool.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "base", cmd.Description)
+	assert.Equal(t, 1, len(cmd.Flags))
+	assert.Equal(t, 1, len(warnings))
+	assert.Equal(t, filepath.Join(dir, "tool.overlay.yaml")+":3:5: [0].command.description: already set (use replace)", warnings[0].Error())
+}
+
+func TestApplyComments(t *testing.T) {
+	var base Command
+	if err := yaml.Unmarshal([]byte(`name: root
+commands:
+  # legacy comment
+  - name: legacy # legacy line
+    description: legacy command
+  # current comment
+  - name: current # current line
+    description: current command
+`), &base); err != nil {
+		t.Fatal(err)
+	}
+
+	var overlays []Overlay
+	if err := yaml.Unmarshal([]byte(`- path: [legacy]
+  operation: remove
+- operation: add
+  command:
+    commands:
+      - name: added
+        description: added command
+`), &overlays); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := base.Apply(overlays...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := yaml.Marshal(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `name: root
+commands:
+    # current comment
+    - name: current # current line
+      description: current command
+    - name: added
+      description: added command
+`, string(m))
+}
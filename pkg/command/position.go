@@ -0,0 +1,218 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a location within a spec file.
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (p Position) String() string {
+	switch {
+	case p.Line == 0:
+		return p.File
+	case p.File == "":
+		return fmt.Sprintf("%v:%v", p.Line, p.Column)
+	default:
+		return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Column)
+	}
+}
+
+// Error is an error at a key path (e.g. `commands[3].flags["-x, --foo="]`) within a spec.
+type Error struct {
+	Position Position
+	Path     string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	s := make([]string, 0, 3)
+	if position := e.Position.String(); position != "" {
+		s = append(s, position)
+	}
+	if e.Path != "" {
+		s = append(s, e.Path)
+	}
+	return strings.Join(append(s, e.Err.Error()), ": ")
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// WrapError prefixes the key path of given error.
+func WrapError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		e.Path = joinPaths(path, e.Path)
+		return err
+	}
+	return &Error{Path: path, Err: err}
+}
+
+func nodeError(node *yaml.Node, path string, err error) error {
+	if err := WrapError(err, path); err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Position.Line == 0 {
+			e.Position.Line = node.Line
+			e.Position.Column = node.Column
+		}
+		return err
+	}
+	return nil
+}
+
+func fileError(file string, err error) error {
+	var e *Error
+	switch {
+	case !errors.As(err, &e):
+		return fmt.Errorf("%v: %w", file, err)
+	case e.Position.File == "":
+		e.Position.File = file
+	}
+	return err
+}
+
+// Key is a map key within a key path.
+type Key string
+
+// JoinPath appends given fields (string), indices (int) and keys (Key) to a key path.
+func JoinPath(path string, elems ...any) string {
+	for _, elem := range elems {
+		switch elem := elem.(type) {
+		case int:
+			path += fmt.Sprintf("[%v]", elem)
+		case Key:
+			path += fmt.Sprintf("[%#v]", string(elem))
+		case string:
+			if path != "" {
+				path += "."
+			}
+			path += elem
+		}
+	}
+	return path
+}
+
+func joinPaths(path, other string) string {
+	switch {
+	case path == "":
+		return other
+	case other == "":
+		return path
+	case strings.HasPrefix(other, "["):
+		return path + other
+	default:
+		return path + "." + other
+	}
+}
+
+func splitPath(path string) ([]any, error) {
+	r := regexp.MustCompile(`^(?:\.?([^.\[\]"]+)|\[(\d+)\]|\[("(?:[^"\\]|\\.)*")\])`)
+
+	elems := make([]any, 0)
+	for path != "" {
+		matches := r.FindStringSubmatch(path)
+		switch {
+		case matches == nil:
+			return nil, fmt.Errorf("malformed path: %#v", path)
+		case matches[1] != "":
+			elems = append(elems, matches[1])
+		case matches[2] != "":
+			index, err := strconv.Atoi(matches[2])
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, index)
+		default:
+			key, err := strconv.Unquote(matches[3])
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, Key(key))
+		}
+		path = path[len(matches[0]):]
+	}
+	return elems, nil
+}
+
+type origin struct {
+	node     *yaml.Node
+	file     string
+	files    map[*yaml.Node]string // included files by their node
+	overlays []string              // applied overlay files
+}
+
+// Position returns the closest position of given key path (e.g. `commands[3].flags["-x, --foo="]`).
+// It is only known for commands that were unmarshalled from YAML.
+func (c Command) Position(path string) Position {
+	if c.origin == nil {
+		return Position{}
+	}
+
+	node, file := c.origin.node, c.origin.file
+	position := Position{File: file, Line: node.Line, Column: node.Column}
+
+	elems, err := splitPath(path)
+	if err != nil {
+		return position
+	}
+
+	for _, elem := range elems {
+		var next, key *yaml.Node
+		switch elem := elem.(type) {
+		case int:
+			if node.Kind == yaml.SequenceNode && elem < len(node.Content) {
+				next = node.Content[elem]
+			}
+		case string:
+			key, next = lookup(node, elem)
+		case Key:
+			key, next = lookup(node, string(elem))
+		}
+
+		if next == nil {
+			break
+		}
+
+		if key != nil {
+			position = Position{File: file, Line: key.Line, Column: key.Column}
+		}
+		if included, ok := c.origin.files[next]; ok {
+			file = included
+		}
+		if key == nil {
+			position = Position{File: file, Line: next.Line, Column: next.Column}
+		}
+		node = next
+	}
+	return position
+}
+
+// Locate sets the position of given error based on its key path.
+func (c Command) Locate(err error) error {
+	var e *Error
+	if c.origin != nil && errors.As(err, &e) {
+		e.Position = c.Position(e.Path)
+	}
+	return err
+}
+
+func lookup(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if index := indexOf(node, key); index >= 0 {
+		return node.Content[index], node.Content[index+1]
+	}
+	return nil, nil
+}
@@ -0,0 +1,98 @@
+package command
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestUnmarshalError(t *testing.T) {
+	test := func(spec, expected string) {
+		t.Run(expected, func(t *testing.T) {
+			var cmd Command
+			err := yaml.Unmarshal([]byte(spec), &cmd)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			assert.Equal(t, expected, err.Error())
+		})
+	}
+
+	test(`name: root
+flags:
+  -x, --foo=: valid
+  --foo bar: invalid
+`, `4:3: flags["--foo bar"]: flag syntax invalid: --foo bar`)
+
+	test(`name: root
+commands:
+  - name: one
+  - name: two
+    persistentflags:
+      --bar: [invalid]
+`, `6:14: commands[1].persistentflags["--bar"]: invalid type for FlagSet`)
+
+	test(`name: root
+commands:
+  - name: one
+    commands:
+      - name: two
+        run: {invalid: type}
+`, `6:14: commands[0].commands[0].run: invalid type for Run: expected string or array`)
+}
+
+func TestPosition(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.yaml": `name: root
+flags:
+  -x, --foo*=: foo flag
+commands:
+  - name: sub
+    completion:
+      flag:
+        foo: [one, two]
+  - $ref: included.yaml
+`,
+		"included.yaml": `name: included
+persistentflags:
+  --bar=: bar flag
+`,
+	})
+
+	cmd, err := Load(filepath.Join(dir, "root.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Join(dir, "root.yaml")
+	included := filepath.Join(dir, "included.yaml")
+
+	assert.Equal(t, Position{File: root, Line: 1, Column: 1}, cmd.Position(""))
+	assert.Equal(t, Position{File: root, Line: 3, Column: 3}, cmd.Position(`flags["-x, --foo*="]`))
+	assert.Equal(t, Position{File: root, Line: 3, Column: 3}, cmd.Position(`flags["-x, --foo=*"]`))
+	assert.Equal(t, Position{File: root, Line: 8, Column: 20}, cmd.Position(`commands[0].completion.flag["foo"][1]`))
+	assert.Equal(t, Position{File: included, Line: 1, Column: 1}, cmd.Position(`commands[1]`))
+	assert.Equal(t, Position{File: included, Line: 3, Column: 3}, cmd.Position(`commands[1].persistentflags["--bar="]`))
+	assert.Equal(t, Position{File: root, Line: 4, Column: 1}, cmd.Position(`commands[5]`))
+}
+
+func TestLoadError(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.yaml": `name: root
+commands:
+  - $ref: included.yaml
+`,
+		"included.yaml": `name: included
+flags:
+  invalid: flag
+`,
+	})
+
+	_, err := Load(filepath.Join(dir, "root.yaml"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	assert.Equal(t, filepath.Join(dir, "root.yaml")+":3:5: "+filepath.Join(dir, "included.yaml")+`:3:3: flags["invalid"]: flag syntax invalid: invalid`, err.Error())
+}
@@ -47,10 +47,12 @@ func (r *Run) UnmarshalYAML(value *yaml.Node) error {
 
 	var alias []string
 	if err := value.Decode(&alias); err != nil {
-		return err
+		return nodeError(value, "", errors.New("invalid type for Run: expected string or array"))
 	}
 
 	var err error
-	*r, err = Alias(alias...)
-	return err
+	if *r, err = Alias(alias...); err != nil {
+		return nodeError(value, "", err)
+	}
+	return nil
 }
@@ -0,0 +1,189 @@
+package command
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/carapace-sh/carapace-spec/internal/shebang"
+	"gopkg.in/yaml.v3"
+)
+
+// Validate checks the command for structural problems like references to unknown flags or duplicate subcommands.
+// The problems are returned as ValidationErrors.
+func (c Command) Validate() error {
+	v := &validator{}
+	v.command("", c, nil)
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	for _, err := range v.errs {
+		err.Position = c.Position(err.Path)
+	}
+	return ValidationErrors(v.errs)
+}
+
+// ValidationErrors are the problems found by Validate, each with the key path it occurred at.
+type ValidationErrors []*Error
+
+func (e ValidationErrors) Error() string {
+	s := make([]string, 0, len(e))
+	for _, err := range e {
+		s = append(s, err.Error())
+	}
+	return strings.Join(s, "\n")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+type validator struct {
+	errs []*Error
+}
+
+func (v *validator) report(path, format string, a ...any) {
+	v.errs = append(v.errs, &Error{Path: path, Err: fmt.Errorf(format, a...)})
+}
+
+func (v *validator) command(path string, c Command, inherited []FlagSet) {
+	flags := make(map[string]bool) // including inherited persistent flags
+	local := make(map[string]bool) // flags of the command (as flag groups can't reference inherited ones)
+	for index, fs := range append(inherited, c.Flags, c.PersistentFlags) {
+		for _, f := range fs {
+			flags[f.Name()] = true
+			local[f.Name()] = local[f.Name()] || index >= len(inherited)
+		}
+	}
+
+	for _, field := range []struct {
+		name    string
+		flagSet FlagSet
+	}{{"flags", c.Flags}, {"persistentflags", c.PersistentFlags}} {
+		for _, name := range slices.Sorted(maps.Keys(field.flagSet)) {
+			if f := field.flagSet[name]; f.ShorthandDeprecated != "" && f.Shorthand == "" {
+				v.report(JoinPath(path, field.name, Key(f.format()), "shorthanddeprecated"), "flag has no shorthand: %#v", f.Name())
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Completion.Flag)) {
+		if !flags[name] {
+			v.report(JoinPath(path, "completion", "flag", Key(name)), "unknown flag: %#v", name)
+		}
+		v.action(JoinPath(path, "completion", "flag", Key(name)), c.Completion.Flag[name], flags)
+	}
+	for index, a := range c.Completion.Positional {
+		v.action(JoinPath(path, "completion", "positional", index), a, flags)
+	}
+
+	for _, section := range []struct {
+		name   string
+		groups [][]string
+	}{{"exclusiveflags", c.ExclusiveFlags}, {"requiredtogether", c.RequiredTogether}, {"onerequired", c.OneRequired}} {
+		for index, group := range section.groups {
+			for i, name := range group {
+				if !local[name] {
+					v.report(JoinPath(path, section.name, index, i), "unknown flag: %#v", name)
+				}
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Requires)) {
+		if !local[name] {
+			v.report(JoinPath(path, "requires", Key(name)), "unknown flag: %#v", name)
+		}
+		for index, required := range c.Requires[name] {
+			if !local[required] {
+				v.report(JoinPath(path, "requires", Key(name), index), "unknown flag: %#v", required)
+			}
+		}
+	}
+
+	if c.Run != "" {
+		v.run(JoinPath(path, "run"), c.Run)
+	}
+	v.args(JoinPath(path, "args"), c)
+
+	names := make(map[string]string)
+	for index, subcommand := range c.Commands {
+		subcommandPath := JoinPath(path, "commands", index)
+		name := strings.Split(subcommand.Name, " ")[0]
+		if name == "" {
+			v.report(JoinPath(subcommandPath, "name"), "missing name")
+		} else if other, ok := names[name]; ok {
+			v.report(JoinPath(subcommandPath, "name"), "duplicate name %#v (already used by %v)", name, other)
+		} else {
+			names[name] = subcommandPath
+		}
+
+		for i, alias := range subcommand.Aliases {
+			if other, ok := names[alias]; ok {
+				v.report(JoinPath(subcommandPath, "aliases", i), "duplicate alias %#v (already used by %v)", alias, other)
+			} else {
+				names[alias] = subcommandPath
+			}
+		}
+		v.command(subcommandPath, subcommand, append(inherited, c.PersistentFlags))
+	}
+}
+
+func (v *validator) action(path string, a Action, flags map[string]bool) {
+	for index, condition := range a.When {
+		for _, name := range slices.Sorted(maps.Keys(condition.Flag)) {
+			if !flags[name] {
+				v.report(JoinPath(path, "when", index, "flag", Key(name)), "unknown flag: %#v", name)
+			}
+		}
+	}
+}
+
+func (v *validator) args(path string, c Command) {
+	a := c.Args
+	switch {
+	case a.Exact < 0 || a.Min < 0 || a.Max < 0:
+		v.report(path, "negative amount of arguments")
+	case a.None && (a.Exact != 0 || a.Min != 0 || a.Max != 0):
+		v.report(JoinPath(path, "none"), "conflicts with exact, min and max")
+	case a.Exact != 0 && (a.Min != 0 || a.Max != 0):
+		v.report(JoinPath(path, "exact"), "conflicts with min and max")
+	case a.Max != 0 && a.Min > a.Max:
+		v.report(JoinPath(path, "min"), "exceeds max: %v > %v", a.Min, a.Max)
+	}
+
+	if a.Valid {
+		_, ok := Action{Values: c.Completion.PositionalAny}.StaticValues()
+		for _, positional := range c.Completion.Positional {
+			if _, static := positional.StaticValues(); static {
+				ok = true
+			}
+		}
+		if !ok {
+			v.report(JoinPath(path, "valid"), "no positional completion with static values")
+		}
+	}
+}
+
+func (v *validator) run(path string, r Run) {
+	switch r.Type() {
+	case "alias":
+		var alias []string
+		if err := yaml.Unmarshal([]byte(r), &alias); err != nil || len(alias) == 0 {
+			v.report(path, "malformed alias: %#v", r)
+		}
+	case "script":
+		if _, err := shebang.Parse(string(r)); err != nil {
+			v.report(path, "%v", err)
+		}
+	case "macro":
+		// macros are verified by the linter
+	default:
+		v.report(path, "invalid run: expected macro, script or alias")
+	}
+}
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateShorthandDeprecated(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte(`name: example
+flags:
+  -v, --verbose: {description: verbose output, shorthanddeprecated: use --verbose instead}
+  --quiet: {description: quiet output, shorthanddeprecated: use --quiet instead}
+`), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cmd.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	assert.Equal(t, `4:40: flags["--quiet"].shorthanddeprecated: flag has no shorthand: "quiet"`, err.Error())
+}
+
+func TestValidationErrors(t *testing.T) {
+	var cmd Command
+	if err := yaml.Unmarshal([]byte(`name: example
+commands:
+  - name: sub
+  - name: sub
+exclusiveflags:
+  - [unknown]
+`), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	var validationErrs ValidationErrors
+	if !errors.As(fmt.Errorf("wrapped: %w", cmd.Validate()), &validationErrs) {
+		t.Fatal("expected validation errors")
+	}
+	paths := make([]string, 0, len(validationErrs))
+	for _, err := range validationErrs {
+		paths = append(paths, err.Path)
+	}
+	assert.Equal(t, []string{"exclusiveflags[0][0]", "commands[1].name"}, paths)
+
+	if err := (Command{Name: "valid"}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,227 @@
+// Package doc renders reference documentation of a spec.
+package doc
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+)
+
+// Generate renders a page for given command and each of its subcommands (filename -> content).
+// Hidden and deprecated commands and flags are omitted (as in the help output).
+//
+// Supported formats are `man`, `markdown` and `html`.
+func Generate(cmd command.Command, format string) (map[string][]byte, error) {
+	var render func(p page) string
+	var extension string
+	switch format {
+	case "man":
+		render, extension = renderMan, ".1"
+	case "markdown":
+		render, extension = renderMarkdown, ".md"
+	case "html":
+		render, extension = renderHTML, ".html"
+	default:
+		return nil, fmt.Errorf("unknown format: %#v", format)
+	}
+
+	pages := make(map[string][]byte)
+	for _, p := range newPages(cmd) {
+		pages[strings.Join(p.path, "-")+extension] = []byte(render(p))
+	}
+	return pages, nil
+}
+
+// Filenames returns the filenames of given pages in the order of the commands.
+func Filenames(pages map[string][]byte) []string {
+	return slices.SortedFunc(maps.Keys(pages), func(a, b string) int {
+		// sort `root-sub` after `root` but before `root-sub-subsub` and `root-sub2`
+		key := func(s string) string { return strings.ReplaceAll(strings.TrimSuffix(s, filepath.Ext(s)), "-", "\x00") }
+		return strings.Compare(key(a), key(b))
+	})
+}
+
+type page struct {
+	path        []string // names of the command and its parents
+	synopsis    string
+	description string
+	flags       []flag
+	inherited   []flag // persistent flags of parent commands
+	arguments   []argument
+	examples    [][2]string // command, description
+	commands    [][2]string // name, description
+}
+
+type flag struct {
+	name        string
+	definition  string // e.g. `-f, --file=FILE`
+	description string
+	notes       []string // modifiers like `required` or `repeatable`
+}
+
+type argument struct {
+	name        string
+	description string
+}
+
+func newPages(root command.Command) []page {
+	pages := make([]page, 0)
+	var walk func(c command.Command, path []string, inherited []command.FlagSet)
+	walk = func(c command.Command, path []string, inherited []command.FlagSet) {
+		path = append(slices.Clip(path), strings.Fields(c.Name + " ")[0])
+		pages = append(pages, newPage(c, path, inherited))
+		for _, subcommand := range c.Commands {
+			if !subcommand.Hidden && subcommand.Deprecated == "" && strings.TrimSpace(subcommand.Name) != "" {
+				walk(subcommand, path, append(slices.Clip(inherited), c.PersistentFlags))
+			}
+		}
+	}
+	walk(root, nil, nil)
+	return pages
+}
+
+func newPage(c command.Command, path []string, inherited []command.FlagSet) page {
+	p := page{
+		path:        path,
+		description: strings.TrimSpace(c.Documentation.Command),
+	}
+	if p.description == "" {
+		p.description = strings.TrimSpace(c.Description)
+	}
+
+	parents := strings.Join(path[:len(path)-1], " ")
+	if usage := strings.TrimSpace(c.Name); strings.Contains(usage, " ") {
+		p.synopsis = strings.TrimSpace(parents + " " + usage) // usage string of the command
+	} else {
+		p.synopsis = strings.Join(path, " ")
+		if len(c.Flags) > 0 || len(c.PersistentFlags) > 0 || len(inherited) > 0 {
+			p.synopsis += " [flags]"
+		}
+		if len(c.Commands) > 0 {
+			p.synopsis += " <command>"
+		}
+		if len(c.Documentation.Positional) > 0 || c.Documentation.PositionalAny != "" {
+			p.synopsis += " [args...]"
+		}
+	}
+
+	for _, fs := range []command.FlagSet{c.Flags, c.PersistentFlags} {
+		p.flags = append(p.flags, newFlags(fs, c.Documentation.Flag)...)
+	}
+	slices.SortFunc(p.flags, func(a, b flag) int { return strings.Compare(a.name, b.name) })
+	for _, fs := range inherited {
+		p.inherited = append(p.inherited, newFlags(fs, nil)...)
+	}
+	slices.SortFunc(p.inherited, func(a, b flag) int { return strings.Compare(a.name, b.name) })
+
+	for index, s := range c.Documentation.Positional {
+		p.arguments = append(p.arguments, argument{fmt.Sprintf("%v", index+1), s})
+	}
+	if s := c.Documentation.PositionalAny; s != "" {
+		p.arguments = append(p.arguments, argument{"...", s})
+	}
+	for index, s := range c.Documentation.Dash {
+		p.arguments = append(p.arguments, argument{fmt.Sprintf("-- %v", index+1), s})
+	}
+	if s := c.Documentation.DashAny; s != "" {
+		p.arguments = append(p.arguments, argument{"-- ...", s})
+	}
+
+	for _, example := range slices.Sorted(maps.Keys(c.Examples)) {
+		p.examples = append(p.examples, [2]string{example, c.Examples[example]})
+	}
+
+	for _, subcommand := range c.Commands {
+		if name := strings.Fields(subcommand.Name + " ")[0]; !subcommand.Hidden && subcommand.Deprecated == "" && name != "" {
+			p.commands = append(p.commands, [2]string{name, subcommand.Description})
+		}
+	}
+	return p
+}
+
+func newFlags(fs command.FlagSet, documentation map[string]string) []flag {
+	flags := make([]flag, 0)
+	for _, f := range fs {
+		if f.Hidden || f.Deprecated != "" {
+			continue
+		}
+
+		description := f.Description
+		if s := documentation[f.Name()]; s != "" {
+			description = strings.TrimSpace(description + "\n\n" + s)
+		}
+		flags = append(flags, flag{
+			name:        f.Name(),
+			definition:  flagDefinition(f),
+			description: description,
+			notes:       flagNotes(f),
+		})
+	}
+	return flags
+}
+
+// flagDefinition formats the flag as used on the command line (e.g. `-f, --file=FILE`).
+func flagDefinition(f command.Flag) string {
+	names := make([]string, 0, 2)
+	if f.Shorthand != "" {
+		names = append(names, "-"+f.Shorthand)
+	}
+	switch {
+	case f.Longhand == "":
+	case f.NameAsShorthand:
+		names = append(names, "-"+f.Longhand)
+	default:
+		names = append(names, "--"+f.Longhand)
+	}
+	definition := strings.Join(names, ", ")
+
+	if !f.Value {
+		return definition
+	}
+	value := strings.ToUpper(f.Type)
+	if value == "" {
+		value = "VALUE"
+	}
+	if f.Optarg {
+		return fmt.Sprintf("%v[=%v]", definition, value)
+	}
+	return fmt.Sprintf("%v=%v", definition, value)
+}
+
+func flagNotes(f command.Flag) []string {
+	notes := make([]string, 0)
+	if f.Required {
+		notes = append(notes, "required")
+	}
+	if f.Repeatable {
+		notes = append(notes, "repeatable")
+	}
+	if f.Optarg {
+		notes = append(notes, "optional argument")
+	}
+	if f.Nargs != 0 {
+		notes = append(notes, fmt.Sprintf("nargs: %v", f.Nargs))
+	}
+	if len(f.Enum) > 0 {
+		notes = append(notes, fmt.Sprintf("values: %v", strings.Join(f.Enum, ", ")))
+	}
+	if f.Default != "" {
+		notes = append(notes, fmt.Sprintf("default: %v", f.Default))
+	}
+	if f.Env != "" {
+		notes = append(notes, fmt.Sprintf("env: %v", f.Env))
+	}
+	return notes
+}
+
+// describe joins the description of a flag with its notes.
+func (f flag) describe() string {
+	if len(f.notes) == 0 {
+		return f.description
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v (%v)", f.description, strings.Join(f.notes, ", ")))
+}
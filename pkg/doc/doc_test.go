@@ -0,0 +1,84 @@
+package doc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const spec = `
+name: example
+description: example command
+persistentflags:
+  -v, --verbose: verbose output
+commands:
+  - name: deploy [-e env] target
+    description: deploy a target
+    flags:
+      -e, --env=!: environment
+      --tag=*: tags
+      --level?: {description: log level, enum: [info, debug]}
+      --pair=: {description: pair of values, nargs: 2}
+      --secret&: hidden
+    documentation:
+      command: |
+        Deploys a target.
+        .dots are escaped
+      flag:
+        env: the environment
+      positional: [target to deploy]
+      dashany: arguments passed to the hook
+    examples:
+      example deploy -e prod web: deploy web to production
+  - name: hidden
+    hidden: true
+`
+
+func generate(t *testing.T, format string) map[string][]byte {
+	var cmd command.Command
+	if err := yaml.Unmarshal([]byte(spec), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := Generate(cmd, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pages
+}
+
+func TestMarkdown(t *testing.T) {
+	pages := generate(t, "markdown")
+	assert.Equal(t, "example.md\nexample-deploy.md", strings.Join(Filenames(pages), "\n"))
+	assert.Equal(t, "# example deploy\n\nDeploys a target.\n.dots are escaped\n\n## Synopsis\n\n```\nexample deploy [-e env] target\n```\n\n## Flags\n\n| Flag | Description |\n| --- | --- |\n| `-e, --env=VALUE` | environment<br><br>the environment (required) |\n| `--level[=VALUE]` | log level (optional argument, values: info, debug) |\n| `--pair=VALUE` | pair of values (nargs: 2) |\n| `--tag=VALUE` | tags (repeatable) |\n\n## Inherited Flags\n\n| Flag | Description |\n| --- | --- |\n| `-v, --verbose` | verbose output |\n\n## Arguments\n\n| Position | Description |\n| --- | --- |\n| 1 | target to deploy |\n| -- ... | arguments passed to the hook |\n\n## Examples\n\ndeploy web to production\n\n```sh\nexample deploy -e prod web\n```\n", string(pages["example-deploy.md"]))
+}
+
+func TestMan(t *testing.T) {
+	pages := generate(t, "man")
+	page := string(pages["example-deploy.1"])
+	for _, expected := range []string{
+		".TH EXAMPLE\\-DEPLOY 1\n",
+		".SH NAME\nexample\\-deploy \\- Deploys a target.\n",
+		".SH DESCRIPTION\nDeploys a target.\n\\&.dots are escaped\n",
+		".TP\n\\fB\\-e, \\-\\-env=VALUE\\fR\nenvironment\n\nthe environment (required)\n",
+		".SH SEE ALSO\n\\fBexample\\fR(1)\n",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("missing %#v in:\n%v", expected, page)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	pages := generate(t, "html")
+	if page := string(pages["example.html"]); !strings.Contains(page, `<tr><td><a href="example-deploy.html">deploy</a></td><td>deploy a target</td></tr>`) {
+		t.Errorf("missing subcommand link in:\n%v", page)
+	}
+
+	if _, err := Generate(command.Command{Name: "example"}, "unknown"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
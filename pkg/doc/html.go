@@ -0,0 +1,61 @@
+package doc
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+func renderHTML(p page) string {
+	var b strings.Builder
+	title := html.EscapeString(strings.Join(p.path, " "))
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%v</title>\n</head>\n<body>\n<h1>%v</h1>\n", title, title)
+	if p.description != "" {
+		fmt.Fprintf(&b, "<p>%v</p>\n", htmlText(p.description))
+	}
+	fmt.Fprintf(&b, "<h2>Synopsis</h2>\n<pre>%v</pre>\n", html.EscapeString(p.synopsis))
+
+	if len(p.commands) > 0 {
+		b.WriteString("<h2>Commands</h2>\n<table>\n<tr><th>Command</th><th>Description</th></tr>\n")
+		for _, c := range p.commands {
+			fmt.Fprintf(&b, "<tr><td><a href=\"%v.html\">%v</a></td><td>%v</td></tr>\n", html.EscapeString(strings.Join(append(p.path, c[0]), "-")), html.EscapeString(c[0]), htmlText(c[1]))
+		}
+		b.WriteString("</table>\n")
+	}
+
+	for _, section := range []struct {
+		title string
+		flags []flag
+	}{{"Flags", p.flags}, {"Inherited Flags", p.inherited}} {
+		if len(section.flags) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "<h2>%v</h2>\n<table>\n<tr><th>Flag</th><th>Description</th></tr>\n", section.title)
+		for _, f := range section.flags {
+			fmt.Fprintf(&b, "<tr><td><code>%v</code></td><td>%v</td></tr>\n", html.EscapeString(f.definition), htmlText(f.describe()))
+		}
+		b.WriteString("</table>\n")
+	}
+
+	if len(p.arguments) > 0 {
+		b.WriteString("<h2>Arguments</h2>\n<table>\n<tr><th>Position</th><th>Description</th></tr>\n")
+		for _, a := range p.arguments {
+			fmt.Fprintf(&b, "<tr><td>%v</td><td>%v</td></tr>\n", html.EscapeString(a.name), htmlText(a.description))
+		}
+		b.WriteString("</table>\n")
+	}
+
+	if len(p.examples) > 0 {
+		b.WriteString("<h2>Examples</h2>\n")
+		for _, e := range p.examples {
+			fmt.Fprintf(&b, "<p>%v</p>\n<pre>%v</pre>\n", htmlText(e[1]), html.EscapeString(e[0]))
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// htmlText escapes given text and retains line breaks.
+func htmlText(s string) string {
+	return strings.ReplaceAll(html.EscapeString(strings.TrimSpace(s)), "\n", "<br>")
+}
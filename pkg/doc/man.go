@@ -0,0 +1,85 @@
+package doc
+
+import (
+	"fmt"
+	"strings"
+)
+
+func renderMan(p page) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH %v 1\n", manEscape(strings.ToUpper(strings.Join(p.path, "-"))))
+	fmt.Fprintf(&b, ".SH NAME\n%v", manEscape(strings.Join(p.path, "-")))
+	if p.description != "" {
+		fmt.Fprintf(&b, " \\- %v", manText(strings.SplitN(p.description, "\n", 2)[0]))
+	}
+	fmt.Fprintf(&b, "\n.SH SYNOPSIS\n.B %v\n", manEscape(p.synopsis))
+
+	if p.description != "" {
+		fmt.Fprintf(&b, ".SH DESCRIPTION\n%v\n", manText(p.description))
+	}
+
+	if len(p.commands) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, c := range p.commands {
+			fmt.Fprintf(&b, ".TP\n\\fB%v\\fR\n%v\n", manEscape(c[0]), manText(c[1]))
+		}
+	}
+
+	for _, section := range []struct {
+		title string
+		flags []flag
+	}{{"OPTIONS", p.flags}, {"INHERITED OPTIONS", p.inherited}} {
+		if len(section.flags) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, ".SH %v\n", section.title)
+		for _, f := range section.flags {
+			fmt.Fprintf(&b, ".TP\n\\fB%v\\fR\n%v\n", manEscape(f.definition), manText(f.describe()))
+		}
+	}
+
+	if len(p.arguments) > 0 {
+		b.WriteString(".SH ARGUMENTS\n")
+		for _, a := range p.arguments {
+			fmt.Fprintf(&b, ".TP\n\\fI%v\\fR\n%v\n", manEscape(a.name), manText(a.description))
+		}
+	}
+
+	if len(p.examples) > 0 {
+		b.WriteString(".SH EXAMPLES\n")
+		for _, e := range p.examples {
+			fmt.Fprintf(&b, ".PP\n%v\n.PP\n.RS\n.nf\n%v\n.fi\n.RE\n", manText(e[1]), manText(e[0]))
+		}
+	}
+
+	if len(p.path) > 1 || len(p.commands) > 0 {
+		related := make([]string, 0)
+		if len(p.path) > 1 {
+			related = append(related, fmt.Sprintf("\\fB%v\\fR(1)", manEscape(strings.Join(p.path[:len(p.path)-1], "-"))))
+		}
+		for _, c := range p.commands {
+			related = append(related, fmt.Sprintf("\\fB%v\\fR(1)", manEscape(strings.Join(append(p.path, c[0]), "-"))))
+		}
+		fmt.Fprintf(&b, ".SH SEE ALSO\n%v\n", strings.Join(related, ", "))
+	}
+	return b.String()
+}
+
+// manEscape escapes given text for use within a roff line (hyphens are escaped as minus for names and options).
+func manEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\e`,
+		"-", `\-`,
+	).Replace(s)
+}
+
+// manText escapes given text for use as roff paragraph.
+func manText(s string) string {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(s), `\`, `\e`), "\n")
+	for index, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[index] = `\&` + line // prevent interpretation as request
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,58 @@
+package doc
+
+import (
+	"fmt"
+	"strings"
+)
+
+func renderMarkdown(p page) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %v\n", strings.Join(p.path, " "))
+	if p.description != "" {
+		fmt.Fprintf(&b, "\n%v\n", p.description)
+	}
+	fmt.Fprintf(&b, "\n## Synopsis\n\n```\n%v\n```\n", p.synopsis)
+
+	if len(p.commands) > 0 {
+		b.WriteString("\n## Commands\n\n| Command | Description |\n| --- | --- |\n")
+		for _, c := range p.commands {
+			fmt.Fprintf(&b, "| [%v](%v.md) | %v |\n", c[0], strings.Join(append(p.path, c[0]), "-"), markdownCell(c[1]))
+		}
+	}
+
+	for _, section := range []struct {
+		title string
+		flags []flag
+	}{{"Flags", p.flags}, {"Inherited Flags", p.inherited}} {
+		if len(section.flags) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %v\n\n| Flag | Description |\n| --- | --- |\n", section.title)
+		for _, f := range section.flags {
+			fmt.Fprintf(&b, "| `%v` | %v |\n", f.definition, markdownCell(f.describe()))
+		}
+	}
+
+	if len(p.arguments) > 0 {
+		b.WriteString("\n## Arguments\n\n| Position | Description |\n| --- | --- |\n")
+		for _, a := range p.arguments {
+			fmt.Fprintf(&b, "| %v | %v |\n", a.name, markdownCell(a.description))
+		}
+	}
+
+	if len(p.examples) > 0 {
+		b.WriteString("\n## Examples\n")
+		for _, e := range p.examples {
+			fmt.Fprintf(&b, "\n%v\n\n```sh\n%v\n```\n", e[1], e[0])
+		}
+	}
+	return b.String()
+}
+
+// markdownCell escapes given text for use in a table cell.
+func markdownCell(s string) string {
+	return strings.NewReplacer(
+		"|", `\|`,
+		"\n", "<br>",
+	).Replace(strings.TrimSpace(s))
+}
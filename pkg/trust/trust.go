@@ -0,0 +1,87 @@
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Store contains the content hashes of trusted specs by their path.
+type Store map[string]string
+
+// Path returns the location of the trust store.
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "carapace-spec", "trust.yaml"), nil
+}
+
+// Load reads the trust store (empty if it does not exist yet).
+func Load() (Store, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make(Store), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := make(Store)
+	if err := yaml.Unmarshal(content, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the trust store.
+func (s Store) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	m, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, m, 0600)
+}
+
+// Trusted checks whether the spec at given path is trusted with given content hash.
+func (s Store) Trusted(path, hash string) bool {
+	trusted, ok := s[path]
+	return ok && trusted == hash
+}
+
+// Hash returns the content hash of given files (the spec and its includes).
+func Hash(files ...string) (string, error) {
+	h := sha256.New()
+	for _, file := range slices.Sorted(slices.Values(files)) {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(file))
+		h.Write([]byte{0})
+		h.Write(content)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,70 @@
+package trust
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // linux
+	t.Setenv("HOME", dir)            // darwin
+	t.Setenv("AppData", dir)         // windows
+
+	s, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Store{}, s)
+
+	s["/path/to/spec.yaml"] = "hash"
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, true, s.Trusted("/path/to/spec.yaml", "hash"))
+	assert.Equal(t, false, s.Trusted("/path/to/spec.yaml", "modified"))
+	assert.Equal(t, false, s.Trusted("/path/to/other.yaml", "hash"))
+}
+
+func TestHash(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.yaml")
+	included := filepath.Join(dir, "included.yaml")
+	for _, file := range []string{spec, included} {
+		if err := os.WriteFile(file, []byte("name: "+filepath.Base(file)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash, err := Hash(spec, included)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reordered, err := Hash(included, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, hash, reordered)
+
+	if err := os.WriteFile(included, []byte("name: modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified, err := Hash(spec, included)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modified == hash {
+		t.Error("expected hash to change with included file")
+	}
+
+	if _, err := Hash(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,11 @@
+package spec
+
+import "os/exec"
+
+// setProcessGroup is a noop on windows as process groups aren't supported.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup only kills the process itself on windows (child processes keep running).
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
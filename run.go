@@ -21,6 +21,51 @@ import (
 
 type run string
 
+// RunE returns a function executing given run (alias, macro or script) like the `run` field of a spec.
+func RunE(s string) func(cmd *cobra.Command, args []string) error {
+	return run(s).Parse()
+}
+
+// ActionRun completes the arguments of given run by delegating to the aliased command.
+func ActionRun(s string) carapace.Action {
+	return carapace.ActionCallback(func(context carapace.Context) carapace.Action {
+		switch command.Run(s).Type() {
+		case "alias":
+			var mArgs []string
+			if err := yaml.Unmarshal([]byte(s), &mArgs); err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+			if len(mArgs) == 0 {
+				return carapace.ActionMessage("empty alias: %#v", s)
+			}
+
+			var err error
+			for index, arg := range mArgs {
+				if mArgs[index], err = context.Envsubst(arg); err != nil {
+					return carapace.ActionMessage(err.Error())
+				}
+			}
+
+			// TODO keep in sync with ActionCarapaceBin in carapace-bridge
+			carapaceCmd := "carapace"
+			if executable, err := os.Executable(); err == nil && filepath.Base(executable) == "carapace" {
+				carapaceCmd = executable // workaround for sandbox tests: directly call executable which was built with "go run"
+			}
+
+			execArgs := []string{mArgs[0], "export", mArgs[0]}
+			execArgs = append(execArgs, mArgs[1:]...)
+			execArgs = append(execArgs, context.Args...)
+			execArgs = append(execArgs, context.Value)
+			return carapace.ActionExecCommand(carapaceCmd, execArgs...)(func(output []byte) carapace.Action {
+				return carapace.ActionImport(output)
+			})
+
+		default:
+			return carapace.ActionValues()
+		}
+	})
+}
+
 func (r run) Parse() func(cmd *cobra.Command, args []string) error {
 	switch command.Run(r).Type() {
 	case "macro":
@@ -36,6 +81,10 @@ func (r run) Parse() func(cmd *cobra.Command, args []string) error {
 
 func (r run) parseAlias() func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		if err := checkTrust(cmd.Root().Annotations[trustAnnotation]); err != nil {
+			return err
+		}
+
 		alias := make([]string, 0)
 		if err := yaml.Unmarshal([]byte(r), &alias); err != nil {
 			return err
@@ -74,6 +123,10 @@ func (r run) parseAlias() func(cmd *cobra.Command, args []string) error {
 
 func (r run) parseMacro() func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		if err := checkTrust(cmd.Root().Annotations[trustAnnotation]); err != nil {
+			return err
+		}
+
 		context := r.context(cmd, nil)
 		context.Args = args // force context.Args contain all args (ignore Value)
 
@@ -84,25 +137,7 @@ func (r run) parseMacro() func(cmd *cobra.Command, args []string) error {
 
 		splitted := strings.Split(string(r), " ||| ")
 
-		m, err := macro.MacroMap[Macro]{
-			"": MacroI(func(s string) carapace.Action {
-				if runtime.GOOS == "windows" {
-					return runAction(cmd, "cmd", s)
-				}
-				return runAction(cmd, "sh", s)
-			}),
-			"bash":   MacroI(func(s string) carapace.Action { return runAction(cmd, "bash", s) }),
-			"cmd":    MacroI(func(s string) carapace.Action { return runAction(cmd, "cmd", s) }),
-			"elvish": MacroI(func(s string) carapace.Action { return runAction(cmd, "elvish", s) }),
-			"fish":   MacroI(func(s string) carapace.Action { return runAction(cmd, "fish", s) }),
-			"ion":    MacroI(func(s string) carapace.Action { return runAction(cmd, "ion", s) }),
-			"nu":     MacroI(func(s string) carapace.Action { return runAction(cmd, "nu", s) }),
-			"osh":    MacroI(func(s string) carapace.Action { return runAction(cmd, "osh", s) }),
-			"pwsh":   MacroI(func(s string) carapace.Action { return runAction(cmd, "pwsh", s) }),
-			"sh":     MacroI(func(s string) carapace.Action { return runAction(cmd, "sh", s) }),
-			"xonsh":  MacroI(func(s string) carapace.Action { return runAction(cmd, "xonsh", s) }),
-			"zsh":    MacroI(func(s string) carapace.Action { return runAction(cmd, "zsh", s) }),
-		}.Lookup(splitted[0])
+		m, err := runMacros(cmd).Lookup(splitted[0])
 		if err != nil {
 			return err
 		}
@@ -112,7 +147,7 @@ func (r run) parseMacro() func(cmd *cobra.Command, args []string) error {
 			if !strings.HasPrefix(s, "$chdir(") { // TODO only chdir modifier accepted at the moment
 				return errors.New("invalid modifier")
 			}
-			action = modifier{action}.Parse(s)
+			action = modifier{Action: action}.Parse(s)
 		}
 
 		action.Invoke(context) // run the command
@@ -120,8 +155,32 @@ func (r run) parseMacro() func(cmd *cobra.Command, args []string) error {
 	}
 }
 
+// runMacros returns the macros supported by `run`.
+func runMacros(cmd *cobra.Command) macro.MacroMap[Macro] {
+	return macro.MacroMap[Macro]{
+		"": MacroI(func(s string) carapace.Action {
+			if runtime.GOOS == "windows" {
+				return runAction(cmd, "cmd", s)
+			}
+			return runAction(cmd, "sh", s)
+		}),
+		"bash":   MacroI(func(s string) carapace.Action { return runAction(cmd, "bash", s) }),
+		"cmd":    MacroI(func(s string) carapace.Action { return runAction(cmd, "cmd", s) }),
+		"elvish": MacroI(func(s string) carapace.Action { return runAction(cmd, "elvish", s) }),
+		"fish":   MacroI(func(s string) carapace.Action { return runAction(cmd, "fish", s) }),
+		"ion":    MacroI(func(s string) carapace.Action { return runAction(cmd, "ion", s) }),
+		"nu":     MacroI(func(s string) carapace.Action { return runAction(cmd, "nu", s) }),
+		"osh":    MacroI(func(s string) carapace.Action { return runAction(cmd, "osh", s) }),
+		"pwsh":   MacroI(func(s string) carapace.Action { return runAction(cmd, "pwsh", s) }),
+		"sh":     MacroI(func(s string) carapace.Action { return runAction(cmd, "sh", s) }),
+		"xonsh":  MacroI(func(s string) carapace.Action { return runAction(cmd, "xonsh", s) }),
+		"zsh":    MacroI(func(s string) carapace.Action { return runAction(cmd, "zsh", s) }),
+	}
+}
+
 func (r run) context(cmd *cobra.Command, args []string) carapace.Context {
 	context := carapace.NewContext(args...)
+	setTrustEnv(cmd, &context)
 	cmd.Flags().VisitAll(func(f *pflag.Flag) { // VisitAll as Visit() skips changed persistent flags of parent commands
 		if f.Changed {
 			if slice, ok := f.Value.(pflag.SliceValue); ok {
@@ -136,6 +195,10 @@ func (r run) context(cmd *cobra.Command, args []string) carapace.Context {
 
 func (r run) parseScript() func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		if err := checkTrust(cmd.Root().Annotations[trustAnnotation]); err != nil {
+			return err
+		}
+
 		context := r.context(cmd, args)
 		for index, arg := range args {
 			context.Setenv(fmt.Sprintf("C_ARG%v", index), arg)
@@ -187,6 +250,10 @@ func (r run) parseScript() func(cmd *cobra.Command, args []string) error {
 
 func runAction(cmd *cobra.Command, shell, command string) carapace.Action {
 	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+		if err := checkTrust(cmd.Root().Annotations[trustAnnotation]); err != nil {
+			return carapace.ActionMessage(err.Error())
+		}
+
 		substituted, err := c.Envsubst(command)
 		if err != nil {
 			return carapace.ActionMessage(err.Error())
@@ -0,0 +1,312 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	addCoreMacro("csv", MacroI(func(s structured) carapace.Action { return s.action(decodeCSV) }))
+	addCoreMacro("json", MacroI(func(s structured) carapace.Action { return s.action(decodeJSON) }))
+	addCoreMacro("yaml", MacroI(func(s structured) carapace.Action { return s.action(decodeYAML) }))
+}
+
+// structured extracts values from structured output of a command or the content of a file.
+type structured struct {
+	Cmd         string `yaml:"cmd,omitempty"`         // command executed in a shell
+	File        string `yaml:"file,omitempty"`        // file to read
+	Value       string `yaml:"value,omitempty"`       // path expression of the values
+	Description string `yaml:"description,omitempty"` // path expression of the descriptions
+	Style       string `yaml:"style,omitempty"`       // path expression of the styles
+}
+
+func (s structured) action(decode func([]byte) (any, error)) carapace.Action {
+	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+		switch {
+		case s.Value == "":
+			return carapace.ActionMessage("missing value")
+		case (s.Cmd == "") == (s.File == ""):
+			return carapace.ActionMessage("either cmd or file must be set")
+		}
+
+		if s.File != "" {
+			abs, err := c.Abs(s.File)
+			if err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+			content, err := os.ReadFile(abs)
+			if err != nil {
+				return carapace.ActionMessage(err.Error())
+			}
+			return s.extract(content, decode)
+		}
+
+		shell := "sh"
+		if runtime.GOOS == "windows" {
+			shell = "cmd"
+		}
+		args, err := shellArgs(shell, s.Cmd, c.Args...)
+		if err != nil {
+			return carapace.ActionMessage(err.Error())
+		}
+		timeout, err := shellTimeout(c)
+		if err != nil {
+			return carapace.ActionMessage(err.Error())
+		}
+		return actionExecCommand(timeout, shell, args...)(func(output []byte) carapace.Action {
+			return s.extract(output, decode)
+		}).Invoke(c).ToA()
+	})
+}
+
+func (s structured) extract(content []byte, decode func([]byte) (any, error)) carapace.Action {
+	data, err := decode(content)
+	if err != nil {
+		return carapace.ActionMessage(err.Error())
+	}
+
+	columns := make([][]string, 0, 3)
+	for _, path := range []string{s.Value, s.Description, s.Style} {
+		if path == "" {
+			columns = append(columns, nil)
+			continue
+		}
+		results, err := query(data, path)
+		if err != nil {
+			return carapace.ActionMessage(err.Error())
+		}
+		if len(columns) > 0 && len(results) != len(columns[0]) {
+			return carapace.ActionMessage("%#v: expected %v results, got %v", path, len(columns[0]), len(results))
+		}
+		column := make([]string, 0, len(results))
+		for _, result := range results {
+			column = append(column, formatResult(result))
+		}
+		columns = append(columns, column)
+	}
+
+	vals := make([]string, 0)
+	for index, value := range columns[0] {
+		if value == "" {
+			continue
+		}
+		vals = append(vals, value)
+		for _, column := range columns[1:] {
+			if column == nil {
+				vals = append(vals, "")
+			} else {
+				vals = append(vals, column[index])
+			}
+		}
+	}
+	return carapace.ActionStyledValuesDescribed(vals...)
+}
+
+// decodeJSON decodes given content with numbers kept verbatim (large integers would lose precision as float64).
+func decodeJSON(content []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid content after top-level value")
+	}
+	return data, nil
+}
+
+func decodeYAML(content []byte) (any, error) {
+	var data any
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeCSV decodes given content into a list of records keyed by the header.
+func decodeCSV(content []byte) (any, error) {
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []any{}, nil
+	}
+
+	records := make([]any, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]any, len(row))
+		for index, field := range row {
+			record[rows[0][index]] = field
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// query evaluates a path expression like `.items[].metadata.name` against given data.
+//
+//   - `.key` and `.["key"]` select the value of a key
+//   - `.[n]` selects the element at given index
+//   - `.[]` selects all elements
+func query(data any, path string) ([]any, error) {
+	if !strings.HasPrefix(path, ".") {
+		return nil, fmt.Errorf("malformed path: %#v", path)
+	}
+
+	results := []any{data}
+	for rest := path; rest != ""; {
+		var step func(any) ([]any, error)
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("malformed path: %#v", path)
+			}
+			var err error
+			if step, err = indexStep(rest[1:end]); err != nil {
+				return nil, fmt.Errorf("malformed path: %#v: %w", path, err)
+			}
+			rest = rest[end+1:]
+
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				continue // `.` or `.[`
+			}
+			step = keyStep(rest[:end])
+			rest = rest[end:]
+
+		default:
+			return nil, fmt.Errorf("malformed path: %#v", path)
+		}
+
+		next := make([]any, 0, len(results))
+		for _, result := range results {
+			selected, err := step(result)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", path, err)
+			}
+			next = append(next, selected...)
+		}
+		results = next
+	}
+	return results, nil
+}
+
+func keyStep(key string) func(any) ([]any, error) {
+	return func(data any) ([]any, error) {
+		switch data := data.(type) {
+		case nil:
+			return []any{nil}, nil
+		case map[string]any:
+			return []any{data[key]}, nil
+		default:
+			return nil, fmt.Errorf("cannot select %#v of %v", key, typeName(data))
+		}
+	}
+}
+
+func indexStep(s string) (func(any) ([]any, error), error) {
+	switch {
+	case s == "":
+		return func(data any) ([]any, error) {
+			switch data := data.(type) {
+			case nil:
+				return []any{}, nil
+			case []any:
+				return data, nil
+			case map[string]any:
+				return nil, errors.New("cannot iterate object")
+			default:
+				return nil, fmt.Errorf("cannot iterate %v", typeName(data))
+			}
+		}, nil
+
+	case strings.HasPrefix(s, `"`):
+		key, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, err
+		}
+		return keyStep(key), nil
+
+	default:
+		index, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		return func(data any) ([]any, error) {
+			switch data := data.(type) {
+			case nil:
+				return []any{nil}, nil
+			case []any:
+				i := index
+				if i < 0 {
+					i += len(data) // relative to end
+				}
+				if i < 0 || i >= len(data) {
+					return []any{nil}, nil
+				}
+				return []any{data[i]}, nil
+			default:
+				return nil, fmt.Errorf("cannot index %v", typeName(data))
+			}
+		}, nil
+	}
+}
+
+func typeName(data any) string {
+	switch data.(type) {
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number, int, int64, uint64, float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", data)
+	}
+}
+
+// formatResult formats a query result as value.
+func formatResult(result any) string {
+	switch result := result.(type) {
+	case nil:
+		return ""
+	case string:
+		return result
+	case json.Number:
+		return result.String()
+	case float64:
+		return strconv.FormatFloat(result, 'f', -1, 64)
+	case []any, map[string]any:
+		m, err := json.Marshal(result)
+		if err != nil {
+			return err.Error()
+		}
+		return string(m)
+	default:
+		return fmt.Sprint(result)
+	}
+}
@@ -0,0 +1,93 @@
+package spec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/carapace-sh/carapace/pkg/assert"
+)
+
+func TestQuery(t *testing.T) {
+	data, err := decodeJSON([]byte(`{"items": [{"name": "one", "number": 1, "tags": ["a"]}, {"name": "two", "number": 2.5}], "key with space": true, "id": 12345678901234567890, "ids": [12345678901234567890]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path     string
+		expected []string
+	}{
+		{".items[].name", []string{"one", "two"}},
+		{".items[].number", []string{"1", "2.5"}},
+		{".items[0].tags", []string{`["a"]`}},
+		{".items[-1].name", []string{"two"}},
+		{".items[].tags[0]", []string{"a", ""}},
+		{".items[5].name", []string{""}},
+		{`.["key with space"]`, []string{"true"}},
+		{".missing[]", []string{}},
+		{".id", []string{"12345678901234567890"}},
+		{".ids", []string{"[12345678901234567890]"}},
+	} {
+		results, err := query(data, tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted := make([]string, 0, len(results))
+		for _, result := range results {
+			formatted = append(formatted, formatResult(result))
+		}
+		assert.Equal(t, tc.expected, formatted)
+	}
+
+	for path, expected := range map[string]string{
+		"items":           `malformed path: "items"`,
+		".items[":         `malformed path: ".items["`,
+		".items[x]":       `malformed path: ".items[x]": strconv.Atoi: parsing "x": invalid syntax`,
+		".items.name":     `.items.name: cannot select "name" of array`,
+		".items[].name[]": `.items[].name[]: cannot iterate string`,
+		".id[]":           `.id[]: cannot iterate number`,
+	} {
+		if _, err := query(data, path); err == nil {
+			t.Errorf("expected error for %#v", path)
+		} else {
+			assert.Equal(t, expected, err.Error())
+		}
+	}
+}
+
+func TestDecodeCSV(t *testing.T) {
+	data, err := decodeCSV([]byte("name,description\none,first\ntwo,second\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := query(data, ".[].description")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []any{"first", "second"}, results)
+}
+
+func TestDecodeYAML(t *testing.T) {
+	data, err := decodeYAML([]byte("items:\n  - name: one\n    count: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := query(data, ".items[].count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "3", formatResult(results[0]))
+}
+
+func TestTypeName(t *testing.T) {
+	assert.Equal(t, "number", typeName(uint64(1)))
+	assert.Equal(t, "time.Time", typeName(time.Time{}))
+}
+
+func TestDecodeJSON(t *testing.T) {
+	if _, err := decodeJSON([]byte(`{} {}`)); err == nil {
+		t.Error("expected error")
+	}
+}
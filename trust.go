@@ -0,0 +1,98 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace-spec/pkg/trust"
+	"github.com/spf13/cobra"
+)
+
+// trustAnnotation marks the root command of a verified spec (contains the path of the spec if it is untrusted).
+const trustAnnotation = "carapace-spec_untrusted"
+
+// untrustedEnv is the context variable passing the trust state to macros
+// (unset if not verified, empty if trusted and otherwise the path of the untrusted spec).
+const untrustedEnv = "CARAPACE_SPEC_UNTRUSTED"
+
+// VerifyTrust verifies given spec against the trust store and marks the command created from it accordingly.
+// Command execution (shell macros, `$exec` and `run`) is disabled if the spec is not trusted with its current content.
+// Completions then return a message and runnable commands an error instead.
+// Specs loaded by `$spec` are verified as well.
+func VerifyTrust(spec *command.Command, cmd *cobra.Command) error {
+	untrusted, err := verifyTrust(spec)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[trustAnnotation] = untrusted
+	return nil
+}
+
+// ActionVerifiedSpec is like ActionSpec but verifies the spec against the trust store (see VerifyTrust).
+func ActionVerifiedSpec(path string) carapace.Action {
+	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+		if _, ok := c.LookupEnv(untrustedEnv); !ok {
+			c.Setenv(untrustedEnv, "")
+		}
+		return ActionSpec(path).Invoke(c).ToA()
+	})
+}
+
+// TrustHash returns the hash trust in given spec is bound to: the spec, its includes and overlays
+// (whether applied or not so that it doesn't depend on how the spec was loaded).
+func TrustHash(cmd *command.Command) (string, error) {
+	overlays, err := cmd.OverlayFiles()
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]string, 0)
+	for _, file := range cmd.Files() {
+		if !slices.Contains(overlays, file) {
+			files = append(files, file)
+		}
+	}
+	return trust.Hash(append(files, overlays...)...)
+}
+
+// verifyTrust returns the path of given spec if it is not trusted.
+func verifyTrust(cmd *command.Command) (string, error) {
+	files := cmd.Files()
+	if len(files) == 0 {
+		return "", nil // not loaded from a file
+	}
+
+	store, err := trust.Load()
+	if err != nil {
+		return "", err
+	}
+	hash, err := TrustHash(cmd)
+	if err != nil {
+		return "", err
+	}
+	if !store.Trusted(files[0], hash) {
+		return files[0], nil
+	}
+	return "", nil
+}
+
+// setTrustEnv passes the trust state of the spec given command belongs to on to macros.
+func setTrustEnv(cmd *cobra.Command, c *carapace.Context) {
+	if untrusted, ok := cmd.Root().Annotations[trustAnnotation]; ok {
+		c.Setenv(untrustedEnv, untrusted)
+	}
+}
+
+// checkTrust returns an error if command execution is disabled for given untrusted spec.
+func checkTrust(untrusted string) error {
+	if untrusted != "" {
+		return fmt.Errorf("untrusted spec: run `carapace-spec trust %v` to allow command execution", untrusted)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace-spec/pkg/trust"
+)
+
+func TestVerifyTrust(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // linux
+	t.Setenv("HOME", dir)            // darwin
+	t.Setenv("AppData", dir)         // windows
+
+	path := filepath.Join(dir, "spec.yaml")
+	if err := os.WriteFile(path, []byte("name: spec\nrun: \"$(echo trusted)\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := command.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	untrustedCmd := Command(*cmd).ToCobra()
+	if err := VerifyTrust(cmd, untrustedCmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTrust(untrustedCmd.Annotations[trustAnnotation]); err == nil {
+		t.Error("expected untrusted spec")
+	}
+	if err := run(cmd.Run).Parse()(untrustedCmd, nil); err == nil {
+		t.Error("expected run to fail for untrusted spec")
+	}
+
+	hash, err := trust.Hash(cmd.Files()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (trust.Store{path: hash}).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	trustedCmd := Command(*cmd).ToCobra()
+	if err := VerifyTrust(cmd, trustedCmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTrust(trustedCmd.Annotations[trustAnnotation]); err != nil {
+		t.Error(err)
+	}
+	if err := run(cmd.Run).Parse()(trustedCmd, nil); err != nil {
+		t.Error(err) // an earlier rejection must not affect later loads
+	}
+	if err := checkTrust(untrustedCmd.Annotations[trustAnnotation]); err == nil {
+		t.Error("expected earlier verification to be unaffected")
+	}
+}
+
+func TestTrustHash(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // linux
+	t.Setenv("HOME", dir)            // darwin
+	t.Setenv("AppData", dir)         // windows
+
+	path := filepath.Join(dir, "spec.yaml")
+	if err := os.WriteFile(path, []byte("name: spec\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "spec.overlay.yaml"), []byte("- operation: add\n  command:\n    flags:\n      --extra: extra flag\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, err := command.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlaid, _, err := command.LoadWithOverlays(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := TrustHash(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlaidHash, err := TrustHash(overlaid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != overlaidHash {
+		t.Error("expected trust to be independent of overlays being applied")
+	}
+}
@@ -0,0 +1,66 @@
+package spec
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// parseAction parses given action which is either a list of values or a conditional completion.
+func parseAction(a command.Action, cmd *cobra.Command) carapace.Action {
+	if !a.IsConditional() {
+		return NewAction(a.Values).Parse(cmd)
+	}
+
+	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+		for _, condition := range a.When {
+			if matches(condition, cmd, c) {
+				return NewAction(condition.Then).Parse(cmd)
+			}
+		}
+		return NewAction(a.Else).Parse(cmd)
+	})
+}
+
+// matches checks whether all flags and positional arguments of given condition match.
+func matches(condition command.Condition, cmd *cobra.Command, c carapace.Context) bool {
+	for name, expected := range condition.Flag {
+		if !FlagMatches(cmd.Flag(name), expected, c.LookupEnv) {
+			return false
+		}
+	}
+
+	for index, expected := range condition.Arg {
+		if index < 0 || index >= len(c.Args) || c.Args[index] != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// FlagMatches checks whether given flag has the expected value (or contains it for slice flags).
+// Flags which weren't passed use the value of their environment variable (if set) or their default.
+//
+// It is used by conditional completion and the code generated for it.
+func FlagMatches(f *pflag.Flag, expected string, lookupEnv func(string) (string, bool)) bool {
+	if f == nil {
+		return false
+	}
+
+	sliceValue, isSlice := f.Value.(interface{ GetSlice() []string }) // pflag.SliceValue or enumValue
+	if value, ok := flagEnv(f, lookupEnv); ok && !f.Changed {
+		if isSlice {
+			return slices.Contains(strings.Split(value, ","), expected)
+		}
+		return value == expected
+	}
+
+	if isSlice {
+		return slices.Contains(sliceValue.GetSlice(), expected)
+	}
+	return f.Value.String() == expected
+}
@@ -0,0 +1,74 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/carapace-sh/carapace-spec/pkg/command"
+	"github.com/carapace-sh/carapace/pkg/assert"
+)
+
+func TestMatches(t *testing.T) {
+	cmd, err := Command{
+		Name: "when",
+		Flags: command.FlagSet{
+			"format": {Longhand: "format", Value: true},
+			"tag":    {Longhand: "tag", Value: true, Repeatable: true},
+		},
+	}.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ParseFlags([]string{"--format", "json", "--tag", "a", "--tag", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := carapace.Context{Args: []string{"deploy"}}
+	for _, tc := range []struct {
+		condition command.Condition
+		expected  bool
+	}{
+		{command.Condition{}, true},
+		{command.Condition{Flag: map[string]string{"format": "json"}}, true},
+		{command.Condition{Flag: map[string]string{"format": "yaml"}}, false},
+		{command.Condition{Flag: map[string]string{"tag": "b"}}, true},
+		{command.Condition{Flag: map[string]string{"unknown": "x"}}, false},
+		{command.Condition{Arg: map[int]string{0: "deploy"}}, true},
+		{command.Condition{Arg: map[int]string{1: "deploy"}}, false},
+		{command.Condition{Flag: map[string]string{"format": "json"}, Arg: map[int]string{0: "destroy"}}, false},
+	} {
+		assert.Equal(t, tc.expected, matches(tc.condition, cmd, c))
+	}
+}
+
+func TestFlagMatches(t *testing.T) {
+	cmd, err := Command{
+		Name: "when",
+		Flags: command.FlagSet{
+			"format": {Longhand: "format", Value: true, Default: "yaml", Env: "WHEN_FORMAT"},
+			"tag":    {Longhand: "tag", Value: true, Repeatable: true, Env: "WHEN_TAG"},
+		},
+	}.ToCobraE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noEnv := func(string) (string, bool) { return "", false }
+	assert.Equal(t, true, FlagMatches(cmd.Flag("format"), "yaml", noEnv))
+	assert.Equal(t, false, FlagMatches(cmd.Flag("tag"), "", noEnv))
+	assert.Equal(t, false, FlagMatches(cmd.Flag("unknown"), "", noEnv))
+
+	env := func(key string) (string, bool) {
+		value, ok := map[string]string{"WHEN_FORMAT": "json", "WHEN_TAG": "a,b"}[key]
+		return value, ok
+	}
+	assert.Equal(t, true, FlagMatches(cmd.Flag("format"), "json", env))
+	assert.Equal(t, true, FlagMatches(cmd.Flag("tag"), "b", env))
+
+	if err := cmd.ParseFlags([]string{"--format", "csv", "--tag", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, true, FlagMatches(cmd.Flag("format"), "csv", env))
+	assert.Equal(t, false, FlagMatches(cmd.Flag("tag"), "b", env))
+	assert.Equal(t, true, FlagMatches(cmd.Flag("tag"), "c", env))
+}